@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/flootic/envseal/internal/cli/commands"
 )
 
@@ -11,5 +13,7 @@ var (
 )
 
 func main() {
-	_ = commands.Execute()
+	if err := commands.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
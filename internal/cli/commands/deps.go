@@ -1,14 +1,22 @@
 package commands
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"filippo.io/age"
 	"github.com/flootic/envseal/internal/cli/config"
 	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
 type IdentityStore interface {
-	Load(path string) (*age.X25519Identity, error)
-	Generate() (privKey string, pubKey string, err error)
+	// Load reads the identities held by a file, a directory of identity files
+	// or an env:, fd:, cmd: or file: source. The first one is the primary identity.
+	Load(source string) ([]age.Identity, error)
+	// Generate creates a new identity; postQuantum selects an ML-KEM-768 + X25519 hybrid key.
+	Generate(postQuantum bool) (privKey string, pubKey string, err error)
 }
 
 type SecretsStore interface {
@@ -38,10 +46,85 @@ func (fileManifestStore) Save(m *config.Manifest) error   { return m.Save() }
 
 type identityManager struct{}
 
-func (identityManager) Load(path string) (*age.X25519Identity, error) {
-	return crypto.GetIdentityFromKeyFile(path)
+func (identityManager) Load(source string) ([]age.Identity, error) {
+	path := identityFile(source)
+	if path == "" {
+		content, err := readIdentitySource(source)
+		if err != nil {
+			return nil, err
+		}
+		return parseIdentitySource(source, "", content)
+	}
+
+	identities, err := loadIdentityPath(path)
+
+	// The default identity is complemented by the keys in ~/.envseal/identities/.
+	if defaultPath, _ := config.GetDefaultIdentityFilePath(); path == defaultPath {
+		dir, _ := config.GetDefaultIdentitiesDir()
+		extra, dirErr := loadIdentityPath(dir)
+		switch {
+		case dirErr != nil && !os.IsNotExist(dirErr):
+			return nil, dirErr
+		case err != nil && !os.IsNotExist(err):
+			return nil, err
+		case len(extra) > 0:
+			return append(identities, extra...), nil
+		}
+	}
+
+	if err == nil && len(identities) == 0 {
+		return nil, fmt.Errorf("no identity found in %s", path)
+	}
+	return identities, err
 }
-func (identityManager) Generate() (string, string, error) {
+
+// loadIdentityPath loads an identity file, or every identity file in a
+// directory (skipping hidden files and SSH ".pub" files).
+func loadIdentityPath(path string) ([]age.Identity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadIdentityFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []age.Identity
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".pub") {
+			continue
+		}
+		file := filepath.Join(path, name)
+		ids, err := loadIdentityFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	return identities, nil
+}
+
+// loadIdentityFile reads the identities stored in a file, decrypting them
+// at most once per process.
+func loadIdentityFile(path string) ([]age.Identity, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseIdentitySource(path, path, content)
+}
+
+func (identityManager) Generate(postQuantum bool) (string, string, error) {
+	if postQuantum {
+		return crypto.GenerateHybridIdentity()
+	}
 	return crypto.GenerateIdentity()
 }
 
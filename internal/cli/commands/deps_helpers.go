@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/flootic/envseal/internal/cli/config"
 )
@@ -25,9 +27,18 @@ func checkProjectManifest(deps Deps) func() error {
 	}
 }
 
+func checkIdentitySource(deps Deps) func() error {
+	return func() error {
+		if _, err := deps.IdentityManager.Load(identityFilePath); err != nil {
+			return fmt.Errorf("cannot load identity from %s: %w", identityFilePath, err)
+		}
+		return nil
+	}
+}
+
 func checkSecretsAccess(deps Deps) func() error {
 	return func() error {
-		id, err := deps.IdentityManager.Load(identityFilePath)
+		identities, err := deps.IdentityManager.Load(identityFilePath)
 		if err != nil {
 			return fmt.Errorf("cannot load identity: %w", err)
 		}
@@ -45,7 +56,7 @@ func checkSecretsAccess(deps Deps) func() error {
 			}
 		}()
 
-		if err := sf.Unlock(id); err != nil {
+		if _, err := sf.Unlock(identities...); err != nil {
 			return errors.New("access denied: your key cannot decrypt this file (ask admin to run 'envseal-cli rekey')")
 		}
 		locked = false
@@ -53,3 +64,35 @@ func checkSecretsAccess(deps Deps) func() error {
 		return nil
 	}
 }
+
+func checkUserExpiry(deps Deps) func() error {
+	return func() error {
+		manifest, err := deps.ManifestStore.Load()
+		if err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+
+		var fileKeys []string
+		if sf, err := deps.SecretsStore.Load(secretFilePath); err == nil {
+			fileKeys, _ = sf.GetRecipients()
+		}
+		if stale := expiredWithAccess(manifest, fileKeys); len(stale) > 0 {
+			return fmt.Errorf("expired user(s) can still open %s: %s (run 'envseal rekey --rotate')", secretFilePath, strings.Join(stale, ", "))
+		}
+
+		now := time.Now()
+		var notes []string
+		for _, u := range manifest.AccessControl {
+			switch {
+			case u.Expired(now):
+				notes = append(notes, fmt.Sprintf("%s expired on %s (remove with 'envseal users remove %s')", u.Name, u.ExpiresAt, u.Name))
+			case u.ExpiresSoon(now):
+				notes = append(notes, fmt.Sprintf("%s expires on %s", u.Name, u.ExpiresAt))
+			}
+		}
+		if len(notes) > 0 {
+			return doctorWarning{msg: strings.Join(notes, "; ")}
+		}
+		return nil
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
 const (
@@ -21,6 +24,14 @@ const (
 
 var errDoctorChecksFailed = errors.New(doctorChecksFailed)
 
+// doctorWarning is returned by checks that found something worth fixing
+// but that does not make the run fail.
+type doctorWarning struct {
+	msg string
+}
+
+func (w doctorWarning) Error() string { return w.msg }
+
 type doctorCheck struct {
 	name string
 	fn   func() error
@@ -54,17 +65,29 @@ func runDoctor(cmd *cobra.Command, deps Deps) error {
 }
 
 func buildDoctorChecks(deps Deps) []doctorCheck {
-	checks := []doctorCheck{
-		{
+	var checks []doctorCheck
+
+	if identityFile(identityFilePath) == "" {
+		checks = append(checks, doctorCheck{
+			name: "Identity Source",
+			fn:   checkIdentitySource(deps),
+		})
+	} else {
+		checks = append(checks, doctorCheck{
 			name: "Local Identity",
 			fn:   checkIdentityExists,
-		},
-	}
+		})
+
+		if runtime.GOOS != "windows" {
+			checks = append(checks, doctorCheck{
+				name: "Identity Permissions",
+				fn:   checkIdentityPermissions,
+			})
+		}
 
-	if runtime.GOOS != "windows" {
 		checks = append(checks, doctorCheck{
-			name: "Identity Permissions",
-			fn:   checkIdentityPermissions,
+			name: "Identity Encryption",
+			fn:   checkIdentityEncryption,
 		})
 	}
 
@@ -77,6 +100,10 @@ func buildDoctorChecks(deps Deps) []doctorCheck {
 			name: fmt.Sprintf("Access to %s", secretFilePath),
 			fn:   checkSecretsAccess(deps),
 		},
+		doctorCheck{
+			name: "User Expiry",
+			fn:   checkUserExpiry(deps),
+		},
 	)
 
 	return checks
@@ -86,7 +113,14 @@ func runDoctorChecks(cmd *cobra.Command, checks []doctorCheck) (hasErrors bool)
 	for _, c := range checks {
 		cmd.Printf("Checking %-22s ... ", c.name)
 
-		if err := c.fn(); err != nil {
+		err := c.fn()
+		var warning doctorWarning
+		if errors.As(err, &warning) {
+			cmd.Println(color.YellowString("WARN"))
+			cmd.Printf("  ↳ %v\n", err)
+			continue
+		}
+		if err != nil {
 			cmd.Println(color.RedString("FAILED"))
 			cmd.Printf("  ↳ %v\n", err)
 			hasErrors = true
@@ -99,18 +133,20 @@ func runDoctorChecks(cmd *cobra.Command, checks []doctorCheck) (hasErrors bool)
 }
 
 func checkIdentityExists() error {
-	_, err := os.Stat(identityFilePath)
+	path := identityFile(identityFilePath)
+	_, err := os.Stat(path)
 	if err == nil {
 		return nil
 	}
 	if os.IsNotExist(err) {
-		return fmt.Errorf("file not found at %s (run 'envseal-cli init')", identityFilePath)
+		return fmt.Errorf("file not found at %s (run 'envseal-cli init')", path)
 	}
 	return err
 }
 
 func checkIdentityPermissions() error {
-	info, err := os.Stat(identityFilePath)
+	path := identityFile(identityFilePath)
+	info, err := os.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -120,8 +156,24 @@ func checkIdentityPermissions() error {
 		return fmt.Errorf(
 			"permissions are %o (should be 600). Run: chmod 600 %s",
 			perm,
-			identityFilePath,
+			path,
 		)
 	}
 	return nil
 }
+
+func checkIdentityEncryption() error {
+	path := identityFile(identityFilePath)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if crypto.IsEncryptedKeyFile(content) {
+		return nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "-----BEGIN") {
+		return doctorWarning{msg: "SSH private key has no passphrase (run 'ssh-keygen -p -f " + path + "')"}
+	}
+	return doctorWarning{msg: "private key is stored unencrypted (run 'envseal identity encrypt')"}
+}
@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const editErrorPrefix = "# ERROR: "
+
+func NewEditCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the secrets of a vault in $EDITOR",
+		Long: `Decrypts the vault into a private temporary file (0600, on a RAM-backed
+tmpfs when one is available), opens it in $VISUAL or $EDITOR (default vi)
+and applies the result: changed and new keys are re-encrypted, removed keys
+are deleted. Unchanged values keep their exact ciphertext, so the Git diff
+only shows what was edited.
+
+The temporary file is overwritten and removed when the editor exits, and
+when envseal is terminated. If the edited file is not valid YAML, the editor
+opens again with the error on top; save it unchanged to give up.
+
+Secrets of groups your identity cannot open are not shown and stay as they are.`,
+		Example: `  envseal edit
+  EDITOR="code --wait" envseal edit --env prod`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEdit(cmd, deps)
+		},
+	}
+
+	addNoStrictFlag(cmd)
+	return cmd
+}
+
+func runEdit(cmd *cobra.Command, deps Deps) error {
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+	defer sf.Lock()
+
+	original, err := readAllSecrets(cmd, sf)
+	if err != nil {
+		return err
+	}
+
+	edited, err := editSecrets(original, len(sf.LockedKeys()))
+	if err != nil {
+		return err
+	}
+	if edited == nil {
+		cmd.Println("No changes.")
+		return nil
+	}
+	if len(edited) == 0 && len(original) > 0 {
+		return errors.New("edited file is empty; nothing saved (use 'envseal unset' to remove every secret)")
+	}
+
+	author := authorName(manifest, identities)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var updated, added, removed []string
+	for _, k := range sortedKeys(edited) {
+		old, existed := original[k]
+		if existed && old == edited[k] {
+			continue
+		}
+		if err := sf.SetSecret(k, edited[k]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", k, err)
+		}
+		meta, _, err := sf.SecretMeta(k)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of %s: %w", k, err)
+		}
+		before := meta
+		touchSecretMeta(manifest, &meta, author, now)
+		if !meta.Equal(before) {
+			if err := sf.SetSecretMeta(k, meta, manifest.EncryptSecretMetadata); err != nil {
+				return fmt.Errorf("failed to set metadata of %s: %w", k, err)
+			}
+		}
+		if existed {
+			updated = append(updated, k)
+		} else {
+			added = append(added, k)
+		}
+	}
+	for _, k := range sortedKeys(original) {
+		if _, ok := edited[k]; ok {
+			continue
+		}
+		if err := sf.UnsetSecret(k); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", k, err)
+		}
+		removed = append(removed, k)
+	}
+
+	if len(updated)+len(added)+len(removed) == 0 {
+		cmd.Println("No changes.")
+		return nil
+	}
+
+	if err := sf.Save(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", secretFilePath, err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	for _, change := range []struct {
+		label string
+		keys  []string
+	}{{"Updated", updated}, {"Added", added}, {"Removed", removed}} {
+		if len(change.keys) > 0 {
+			cmd.Printf("%s %s %s\n", green("✓"), change.label, strings.Join(change.keys, ", "))
+		}
+	}
+	cmd.Printf("Updated %s\n", secretFilePath)
+	return nil
+}
+
+// editSecrets lets the user edit secrets in their editor and returns the
+// result, or nil if the file was saved unchanged.
+func editSecrets(secrets map[string]string, hidden int) (map[string]string, error) {
+	dir, err := makeEditDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, strings.TrimSuffix(filepath.Base(secretFilePath), ".enc.yaml")+".yaml")
+
+	cleanup := func() {
+		if info, err := os.Stat(path); err == nil {
+			_ = os.WriteFile(path, make([]byte, info.Size()), 0o600)
+		}
+		_ = os.RemoveAll(dir)
+	}
+	defer cleanup()
+
+	// Ctrl-C belongs to the editor; termination removes the plaintext first.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	go func() {
+		for sig := range sigs {
+			if sig != os.Interrupt {
+				cleanup()
+				os.Exit(1)
+			}
+		}
+	}()
+
+	content, err := renderEditFile(secrets, hidden)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for {
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write temporary file: %w", err)
+		}
+		if err := runEditor(path); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read temporary file: %w", err)
+		}
+
+		if bytes.Equal(data, content) {
+			if lastErr != nil {
+				return nil, fmt.Errorf("edit aborted: %w", lastErr)
+			}
+			return nil, nil
+		}
+
+		edited, err := parseEditFile(data)
+		if err == nil {
+			return edited, nil
+		}
+		lastErr = err
+		fmt.Fprintf(os.Stderr, "Error: %v (reopening the editor)\n", err)
+		content = append([]byte(editErrorPrefix+strings.ReplaceAll(err.Error(), "\n", " ")+"\n"), stripEditErrors(data)...)
+	}
+}
+
+// makeEditDir creates a private directory for the decrypted file, on a
+// RAM-backed filesystem when possible so the plaintext never reaches a disk.
+func makeEditDir() (string, error) {
+	var bases []string
+	if runtime.GOOS == "linux" {
+		bases = append(bases, os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm")
+	}
+	bases = append(bases, os.TempDir())
+
+	var lastErr error
+	for _, base := range bases {
+		if base == "" {
+			continue
+		}
+		dir, err := os.MkdirTemp(base, "envseal-edit-")
+		if err == nil {
+			return dir, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("failed to create temporary directory: %w", lastErr)
+}
+
+func renderEditFile(secrets map[string]string, hidden int) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Editing %s: one KEY: value per line (YAML).\n", secretFilePath)
+	buf.WriteString("# Changed and new keys are re-encrypted, removed keys are deleted.\n")
+	buf.WriteString("# Save the file unchanged to cancel.\n")
+	if hidden > 0 {
+		fmt.Fprintf(&buf, "# %d secret(s) of groups you cannot open are not shown and stay unchanged.\n", hidden)
+	}
+
+	if len(secrets) > 0 {
+		data, err := yaml.Marshal(secrets)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+func parseEditFile(data []byte) (map[string]string, error) {
+	edited := make(map[string]string)
+	if err := yaml.Unmarshal(data, &edited); err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+// stripEditErrors removes the error lines added by a previous attempt.
+func stripEditErrors(data []byte) []byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	lines = slices.DeleteFunc(lines, func(l []byte) bool { return bytes.HasPrefix(l, []byte(editErrorPrefix)) })
+	return bytes.Join(lines, nil)
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, which may include arguments
+// (e.g. "code --wait").
+func runEditor(path string) error {
+	editor := []string{"vi"}
+	if runtime.GOOS == "windows" {
+		editor = []string{"notepad"}
+	}
+	for _, v := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(v)); len(fields) > 0 {
+			editor = fields
+			break
+		}
+	}
+
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -15,28 +15,35 @@ import (
 
 func NewExecCommand(deps Deps) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "exec -- <command> [args...]",
+		Use:   "exec [flags] -- <command> [args...]",
 		Short: "Run a command with injected secrets",
 		Long: `Decrypts secrets in memory and starts a child process with them injected.
 
+The command fails without starting the child if any value is corrupt or
+stored unencrypted, unless --no-strict is given.
+
 Examples:
   envseal-cli exec -- npm start
   envseal-cli exec -- python app.py`,
-		DisableFlagParsing: true,
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runExec(cmd, args, deps)
 		},
 	}
+
+	// Everything after the command name belongs to the child process.
+	cmd.Flags().SetInterspersed(false)
+	addNoStrictFlag(cmd)
 	return cmd
 }
 
-func runExec(_ *cobra.Command, args []string, deps Deps) error {
+func runExec(cmd *cobra.Command, args []string, deps Deps) error {
 	args = stripDoubleDash(args)
 	if len(args) == 0 {
 		return fmt.Errorf("you must specify a command after '--' (e.g. envseal-cli exec -- npm start)")
 	}
 
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
@@ -46,14 +53,14 @@ func runExec(_ *cobra.Command, args []string, deps Deps) error {
 		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
 	}
 
-	if err := sf.Unlock(identity); err != nil {
+	if _, err := sf.Unlock(identities...); err != nil {
 		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
 	}
 	defer sf.Lock()
 
-	vars, err := sf.GetAllSecrets()
+	vars, err := readAllSecrets(cmd, sf)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt secrets: %w", err)
+		return err
 	}
 
 	commandName := args[0]
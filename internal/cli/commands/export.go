@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/envfile"
+	"github.com/flootic/envseal/pkg/filesystem"
+)
+
+var k8sNameInvalidCharsRe = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+func NewExportCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [KEY|PATTERN...]",
+		Short: "Write decrypted secrets in a deployment format",
+		Long: fmt.Sprintf(`Decrypts the vault and writes its secrets, sorted by key, to stdout or to
+--output (created atomically with mode 0600). Each format quotes and escapes
+values so that newlines, spaces, quotes and '#' survive:
+  dotenv      KEY=value, double-quoted with \n, \" and \\ escapes when needed
+  shell       export KEY='value', for eval or source
+  json, yaml  an object of KEY: value
+  docker-env  KEY=value lines for 'docker run --env-file' (no multi-line values)
+  systemd     an EnvironmentFile= for a systemd unit
+  k8s-secret  a Kubernetes Secret manifest (--name, --namespace)
+  tfvars      KEY = "value" lines for Terraform
+
+Arguments select keys, as names or glob patterns (DB_*); --exclude drops
+some. Without arguments every secret you can read is exported.
+
+Formats: %s.`, strings.Join(envfile.ExportFormats, ", ")),
+		Example: `  envseal export > .env
+  envseal export --format json 'DB_*' API_KEY
+  envseal export --env prod --format k8s-secret --name api --namespace web | kubectl apply -f -
+  envseal export --format systemd --output /etc/myapp/env
+  eval "$(envseal export --format shell)"`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().String("format", envfile.FormatDotenv, "Output format: "+strings.Join(envfile.ExportFormats, ", "))
+	cmd.Flags().StringP("output", "o", "", "Write to this file (mode 0600) instead of stdout")
+	cmd.Flags().StringSlice("exclude", nil, "Leave out keys matching these names or glob patterns")
+	cmd.Flags().String("name", "", "Name of the k8s-secret (default: derived from the vault file name)")
+	cmd.Flags().String("namespace", "", "Namespace of the k8s-secret")
+	addNoStrictFlag(cmd)
+	return cmd
+}
+
+func runExport(cmd *cobra.Command, args []string, deps Deps) error {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	name, _ := cmd.Flags().GetString("name")
+	namespace, _ := cmd.Flags().GetString("namespace")
+
+	if !slices.Contains(envfile.ExportFormats, format) {
+		return fmt.Errorf("unknown format %q (use %s)", format, strings.Join(envfile.ExportFormats, ", "))
+	}
+	for _, p := range append(slices.Clone(args), exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+	defer sf.Lock()
+
+	plain, err := readAllSecrets(cmd, sf)
+	if err != nil {
+		return err
+	}
+
+	vars, err := selectExportVars(plain, args, exclude)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = k8sSecretName(secretFilePath)
+	}
+	data, err := envfile.Format(vars, format, envfile.Options{Name: name, Namespace: namespace})
+	if err != nil {
+		return fmt.Errorf("cannot export as %s: %w", format, err)
+	}
+	defer clear(data)
+
+	if output == "" {
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	}
+
+	if err := filesystem.AtomicWriteFile(output, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	cmd.Println(color.GreenString("✓ Exported %d secret(s) to %s", len(vars), output))
+	return nil
+}
+
+// selectExportVars returns the secrets matching one of patterns (all when
+// there are none) and none of exclude, sorted by key. A plain key name that
+// is not in the vault is an error; a glob matching nothing is not.
+func selectExportVars(plain map[string]string, patterns, exclude []string) ([]envfile.Var, error) {
+	matches := func(key string, patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(p string) bool {
+			ok, _ := path.Match(p, key)
+			return ok
+		})
+	}
+
+	for _, p := range patterns {
+		if _, ok := plain[p]; !ok && !strings.ContainsAny(p, `*?[\`) {
+			return nil, fmt.Errorf("%s is not set in %s", p, secretFilePath)
+		}
+	}
+
+	var vars []envfile.Var
+	for _, k := range sortedKeys(plain) {
+		if len(patterns) > 0 && !matches(k, patterns) {
+			continue
+		}
+		if matches(k, exclude) {
+			continue
+		}
+		vars = append(vars, envfile.Var{Key: k, Value: plain[k]})
+	}
+	return vars, nil
+}
+
+// k8sSecretName derives a Kubernetes object name from a vault path:
+// secrets.prod.enc.yaml becomes secrets-prod.
+func k8sSecretName(vaultPath string) string {
+	base := strings.TrimSuffix(filepath.Base(vaultPath), ".enc.yaml")
+	name := k8sNameInvalidCharsRe.ReplaceAllString(strings.ToLower(strings.ReplaceAll(base, ".", "-")), "-")
+	return strings.Trim(name, ".-")
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+// exitKeyNotFound is the exit code of 'get' when the key is not in the
+// vault, so scripts can tell it apart from other failures (exit code 1).
+const exitKeyNotFound = 3
+
+func NewGetCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Print the value of a single secret",
+		Long: fmt.Sprintf(`Decrypts one secret and writes its value to stdout, exactly as stored
+followed by a newline (none with -n). Only that value is decrypted.
+
+If the key is not in the vault, a message is printed to stderr and the exit
+code is %d; any other failure exits with 1.`, exitKeyNotFound),
+		Example: `  PGPASSWORD=$(envseal get DB_PASSWORD) psql -h db.internal
+  envseal get -n TLS_KEY > tls.key
+  envseal get --base64 SIGNING_KEY`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGet(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().BoolP("no-newline", "n", false, "Do not print a trailing newline")
+	cmd.Flags().Bool("base64", false, "Print the value base64-encoded (for binary or multi-line values)")
+	return cmd
+}
+
+func runGet(cmd *cobra.Command, args []string, deps Deps) error {
+	noNewline, err := cmd.Flags().GetBool("no-newline")
+	if err != nil {
+		return err
+	}
+	encode, err := cmd.Flags().GetBool("base64")
+	if err != nil {
+		return err
+	}
+	key := strings.TrimSpace(args[0])
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	// Checked before unlocking so a typo does not prompt for a passphrase.
+	if !slices.Contains(sf.SecretKeys(), key) {
+		exitGetKeyNotFound(cmd, key)
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+
+	value, err := sf.GetSecret(key)
+	sf.Lock()
+	if errors.Is(err, config.ErrKeyNotFound) {
+		exitGetKeyNotFound(cmd, key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	if encode {
+		value = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	if !noNewline {
+		value += "\n"
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), value)
+	return err
+}
+
+func exitGetKeyNotFound(cmd *cobra.Command, key string) {
+	cmd.PrintErrf("Error: %s is not set in %s\n", key, secretFilePath)
+	os.Exit(exitKeyNotFound)
+}
@@ -0,0 +1,460 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+// diffValuesEnvVar asks 'git-diff' to decrypt values when it is run by Git,
+// which gives it no flags: ENVSEAL_DIFF_VALUES=1 git diff.
+const diffValuesEnvVar = "ENVSEAL_DIFF_VALUES"
+
+// gitDiffDriver is the name of the diff driver in .gitattributes and the
+// Git config.
+const gitDiffDriver = "envseal"
+
+func NewGitDiffCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git-diff FILE | PATH OLD-FILE OLD-HEX OLD-MODE NEW-FILE NEW-HEX NEW-MODE",
+		Short: "Show vault changes by key and recipient, for Git diffs",
+		Long: fmt.Sprintf(`Renders vaults so that a Git diff shows which keys were added, removed
+or changed and who gained or lost access, instead of base64 blobs.
+
+With one argument it prints a readable form of the vault, for use as a
+Git textconv filter (set up with 'envseal git-diff setup'). With the seven
+arguments Git passes to an external diff command, it prints a summary of
+the changes between the two versions.
+
+Values are not decrypted unless --values is given or $%s=1 is set, and
+only if your identity can open them. Without them a change shows as a new
+fingerprint of the encrypted value.`, diffValuesEnvVar),
+		Example: `  envseal git-diff setup
+  git diff secrets.enc.yaml
+  ENVSEAL_DIFF_VALUES=1 git log -p secrets.prod.enc.yaml
+  git show HEAD:secrets.enc.yaml > /tmp/v.yaml && envseal git-diff /tmp/v.yaml --vault-name secrets.enc.yaml`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 && len(args) != 7 {
+				return fmt.Errorf("accepts 1 or 7 args, received %d", len(args))
+			}
+			return nil
+		},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 7 {
+				return runGitDiffExternal(cmd, args, deps)
+			}
+			return runGitDiffTextconv(cmd, args[0], deps)
+		},
+	}
+
+	cmd.Flags().Bool("values", false, "Decrypt and show the values you can open (also $"+diffValuesEnvVar+"=1)")
+	cmd.Flags().String("vault-name", "", "File name the vault was encrypted under, when FILE is a copy (default: derived from FILE)")
+	cmd.AddCommand(newGitDiffSetupCommand())
+	return cmd
+}
+
+// vaultView is what a diff shows of one version of a vault.
+type vaultView struct {
+	recipients   map[string][]string // group -> recipient labels
+	groups       map[string]string   // key -> group
+	fingerprints map[string]string   // key -> fingerprint of the stored value
+	metas        map[string]string   // key -> rendered metadata
+	values       map[string]string   // key -> value, when revealed
+	recovery     string
+	notes        []string
+}
+
+func runGitDiffTextconv(cmd *cobra.Command, path string, deps Deps) error {
+	name, _ := cmd.Flags().GetString("vault-name")
+	if name == "" {
+		name = textconvVaultName(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	view, err := loadVaultView(deps, name, data, diffRevealValues(cmd))
+	if err != nil {
+		// Still give Git something to diff.
+		_, werr := cmd.OutOrStdout().Write(data)
+		cmd.PrintErrf("envseal git-diff: %s: %v\n", name, err)
+		return werr
+	}
+	renderVaultView(cmd.OutOrStdout(), name, view)
+	return nil
+}
+
+// textconvVaultName recovers the vault name from a file Git hands to a
+// textconv filter. Blobs not in the work tree are written to temporary
+// files named XXXXXX_<name>.
+func textconvVaultName(path string) string {
+	base := filepath.Base(path)
+	tmp, err := filepath.Abs(os.TempDir())
+	if err != nil {
+		return base
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil || dir != tmp {
+		return base
+	}
+	if len(base) > 7 && base[6] == '_' {
+		return base[7:]
+	}
+	return base
+}
+
+func diffRevealValues(cmd *cobra.Command) bool {
+	reveal, _ := cmd.Flags().GetBool("values")
+	if v, err := strconv.ParseBool(os.Getenv(diffValuesEnvVar)); err == nil && v {
+		reveal = true
+	}
+	return reveal
+}
+
+// loadVaultView parses one version of the vault named name. Empty data (a
+// file that does not exist on that side of the diff) gives an empty view.
+func loadVaultView(deps Deps, name string, data []byte, reveal bool) (*vaultView, error) {
+	view := &vaultView{
+		recipients:   make(map[string][]string),
+		groups:       make(map[string]string),
+		fingerprints: make(map[string]string),
+		metas:        make(map[string]string),
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return view, nil
+	}
+
+	sf, err := config.ParseSecretFile(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, _ := deps.ManifestStore.Load()
+	for _, group := range sf.Groups() {
+		keys, err := sf.GroupRecipients(group)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			view.recipients[group] = append(view.recipients[group], recipientLabel(manifest, k))
+		}
+		slices.Sort(view.recipients[group])
+	}
+	if r := sf.Recovery(); r != nil {
+		custodians := make([]string, 0, len(r.Shares))
+		for _, s := range r.Shares {
+			custodians = append(custodians, s.Custodian)
+		}
+		slices.Sort(custodians)
+		view.recovery = fmt.Sprintf("%d of %s", r.Threshold, strings.Join(custodians, ", "))
+	}
+
+	view.groups = sf.SecretGroups()
+	view.fingerprints = sf.SecretFingerprints()
+
+	if reveal {
+		if err := revealVaultValues(deps, sf, view); err != nil {
+			view.notes = append(view.notes, "values not shown: "+err.Error())
+		}
+		defer sf.Lock()
+	}
+
+	for _, k := range sf.SecretKeys() {
+		meta, ok, err := sf.SecretMeta(k)
+		switch {
+		case errors.Is(err, config.ErrGroupLocked):
+			view.metas[k] = "(encrypted)"
+		case err != nil:
+			view.metas[k] = "(invalid)"
+		case ok:
+			view.metas[k] = formatDiffMeta(meta)
+		}
+	}
+	return view, nil
+}
+
+func revealVaultValues(deps Deps, sf *config.SecretFile, view *vaultView) error {
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return err
+	}
+	if _, err := sf.Unlock(identities...); err != nil {
+		return err
+	}
+	values, invalid, err := sf.GetAllSecretsLenient()
+	if err != nil {
+		return err
+	}
+	view.values = values
+	if invalid != nil {
+		view.notes = append(view.notes, invalid.Error())
+	}
+	return nil
+}
+
+// recipientLabel names a public key after its user in the manifest, if any.
+func recipientLabel(manifest *config.Manifest, pubKey string) string {
+	if manifest != nil {
+		if u, ok := manifest.FindUserByPublicKey(pubKey); ok {
+			return u.Name + " ..." + shortKey(pubKey)
+		}
+	}
+	return "..." + shortKey(pubKey)
+}
+
+func formatDiffMeta(m config.SecretMeta) string {
+	var parts []string
+	add := func(name, v string) {
+		if v != "" {
+			parts = append(parts, name+"="+strconv.Quote(v))
+		}
+	}
+	add("description", m.Description)
+	add("owner", m.Owner)
+	add("rotate_every", m.RotateEvery)
+	add("expires_at", m.ExpiresAt)
+	if !m.UpdatedAt.IsZero() {
+		add("updated_at", m.UpdatedAt.Format(time.RFC3339))
+	}
+	add("updated_by", m.UpdatedBy)
+	return strings.Join(parts, " ")
+}
+
+// renderVaultView prints the textconv form of a vault: one line per
+// recipient and per secret, so that line diffs match key-level changes.
+func renderVaultView(w io.Writer, name string, view *vaultView) {
+	fmt.Fprintf(w, "# envseal vault %s\n", name)
+	for _, n := range view.notes {
+		fmt.Fprintf(w, "# %s\n", n)
+	}
+
+	for _, group := range sortedGroups(view.recipients) {
+		fmt.Fprintf(w, "\nrecipients [%s]:\n", group)
+		for _, r := range view.recipients[group] {
+			fmt.Fprintf(w, "  %s\n", r)
+		}
+	}
+	if view.recovery != "" {
+		fmt.Fprintf(w, "\nrecovery: %s\n", view.recovery)
+	}
+
+	fmt.Fprintln(w, "\nsecrets:")
+	for _, k := range sortedKeys(view.groups) {
+		line := fmt.Sprintf("  %s [%s] %s", k, view.groups[k], view.fingerprints[k])
+		if v, ok := view.values[k]; ok {
+			line += " = " + strconv.Quote(v)
+		}
+		fmt.Fprintln(w, line)
+		if m := view.metas[k]; m != "" {
+			fmt.Fprintf(w, "    meta: %s\n", m)
+		}
+	}
+}
+
+func sortedGroups(m map[string][]string) []string {
+	groups := make([]string, 0, len(m))
+	for g := range m {
+		if g != config.DefaultGroup {
+			groups = append(groups, g)
+		}
+	}
+	slices.Sort(groups)
+	if _, ok := m[config.DefaultGroup]; ok {
+		groups = append([]string{config.DefaultGroup}, groups...)
+	}
+	return groups
+}
+
+// runGitDiffExternal implements GIT_EXTERNAL_DIFF / diff.<driver>.command:
+// path old-file old-hex old-mode new-file new-hex new-mode.
+func runGitDiffExternal(cmd *cobra.Command, args []string, deps Deps) error {
+	path, oldFile, newFile := args[0], args[1], args[4]
+	name, _ := cmd.Flags().GetString("vault-name")
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	reveal := diffRevealValues(cmd)
+
+	load := func(file string) (*vaultView, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		view, err := loadVaultView(deps, name, data, reveal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return view, nil
+	}
+	oldView, err := load(oldFile)
+	if err != nil {
+		return err
+	}
+	newView, err := load(newFile)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	bold := color.New(color.Bold).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintln(w, bold("envseal diff "+path))
+	for _, n := range append(oldView.notes, newView.notes...) {
+		fmt.Fprintf(w, "  # %s\n", n)
+	}
+
+	changes := 0
+	groups := sortedGroups(oldView.recipients)
+	for _, g := range sortedGroups(newView.recipients) {
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	for _, g := range groups {
+		for _, r := range newView.recipients[g] {
+			if !slices.Contains(oldView.recipients[g], r) {
+				fmt.Fprintf(w, "  %s recipient %s [%s]\n", green("+"), r, g)
+				changes++
+			}
+		}
+		for _, r := range oldView.recipients[g] {
+			if !slices.Contains(newView.recipients[g], r) {
+				fmt.Fprintf(w, "  %s recipient %s [%s]\n", red("-"), r, g)
+				changes++
+			}
+		}
+	}
+	if oldView.recovery != newView.recovery {
+		fmt.Fprintf(w, "  %s recovery: %s -> %s\n", yellow("~"), dashIfEmpty(oldView.recovery), dashIfEmpty(newView.recovery))
+		changes++
+	}
+
+	keys := sortedKeys(oldView.groups)
+	for _, k := range sortedKeys(newView.groups) {
+		if _, ok := oldView.groups[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		oldGroup, inOld := oldView.groups[k]
+		newGroup, inNew := newView.groups[k]
+		oldValue, oldRevealed := oldView.values[k]
+		newValue, newRevealed := newView.values[k]
+
+		switch {
+		case !inOld:
+			line := fmt.Sprintf("  %s %s [%s]", green("+"), k, newGroup)
+			if newRevealed {
+				line += " = " + strconv.Quote(newValue)
+			}
+			fmt.Fprintln(w, line)
+		case !inNew:
+			line := fmt.Sprintf("  %s %s [%s]", red("-"), k, oldGroup)
+			if oldRevealed {
+				line += " = " + strconv.Quote(oldValue)
+			}
+			fmt.Fprintln(w, line)
+		default:
+			var what []string
+			if oldGroup != newGroup {
+				what = append(what, fmt.Sprintf("moved %s -> %s", oldGroup, newGroup))
+			}
+			if oldView.fingerprints[k] != newView.fingerprints[k] {
+				switch {
+				case oldRevealed && newRevealed && oldValue == newValue:
+					what = append(what, "re-encrypted, value unchanged")
+				case oldRevealed && newRevealed:
+					what = append(what, strconv.Quote(oldValue)+" -> "+strconv.Quote(newValue))
+				default:
+					what = append(what, "value changed")
+				}
+			}
+			if oldView.metas[k] != newView.metas[k] {
+				what = append(what, "metadata changed")
+			}
+			if len(what) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "  %s %s [%s]: %s\n", yellow("~"), k, newGroup, strings.Join(what, "; "))
+		}
+		changes++
+	}
+
+	if changes == 0 {
+		fmt.Fprintln(w, "  no key or recipient changes")
+	}
+	return nil
+}
+
+func newGitDiffSetupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Configure Git to diff vaults with envseal",
+		Long: fmt.Sprintf(`Marks *.enc.yaml files with "diff=%[1]s" in .gitattributes (to commit,
+so everyone gets it) and declares the %[1]s driver in the repository's
+Git config (local to each clone):
+
+  diff.%[1]s.textconv = /path/to/envseal git-diff
+
+The driver runs this binary by its absolute path; run setup again after
+moving it.
+
+With --external it sets diff.%[1]s.command instead, so 'git diff' prints a
+per-key summary rather than a line diff of the rendered vaults.`, gitDiffDriver),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			external, _ := cmd.Flags().GetBool("external")
+			return runGitDiffSetup(cmd, external)
+		},
+	}
+	cmd.Flags().Bool("external", false, "Use envseal as an external diff command instead of a textconv filter")
+	return cmd
+}
+
+func runGitDiffSetup(cmd *cobra.Command, external bool) error {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return errors.New("not a git repository (or any of the parent directories)")
+	}
+
+	set, unset := "textconv", "command"
+	if external {
+		set, unset = unset, set
+	}
+	if err := gitRun("config", "diff."+gitDiffDriver+"."+set, selfCommand("git-diff")); err != nil {
+		return fmt.Errorf("failed to set git config: %w", err)
+	}
+	// Only one of them may be set: a command takes precedence over textconv.
+	_ = gitRun("config", "--unset", "diff."+gitDiffDriver+"."+unset)
+	_ = gitRun("config", "diff."+gitDiffDriver+".xfuncname", "^(recipients|secrets|recovery).*$")
+
+	changed, err := ensureGitAttribute(filepath.Join(root, ".gitattributes"), "*.enc.yaml", "diff", gitDiffDriver)
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	cmd.Printf("%s diff.%s.%s set in the Git config of this clone\n", green("✓"), gitDiffDriver, set)
+	if changed {
+		cmd.Printf("%s .gitattributes updated; commit it so everyone's diffs use envseal\n", green("✓"))
+	} else {
+		cmd.Printf("%s .gitattributes already uses the %s diff driver\n", green("✓"), gitDiffDriver)
+	}
+	return nil
+}
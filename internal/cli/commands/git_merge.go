@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+// gitMergeDriver is the name of the merge driver in .gitattributes and the
+// Git config.
+const gitMergeDriver = "envseal"
+
+var errMergeConflicts = errors.New("merge conflicts")
+
+func NewGitMergeCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git-merge BASE OURS THEIRS [PATH]",
+		Short: "Three-way merge of a vault, for use as a Git merge driver",
+		Long: `Merges two versions of a vault key by key instead of line by line, as
+the Git merge driver "envseal git-merge %O %A %B %P" (set up with
+'envseal git-merge setup'). The result is written to OURS.
+
+Secrets, their metadata and the recipients of every group are merged
+three-way: a change made on one side only is taken as is, and a key is
+only a conflict when both sides changed it differently. Conflicting keys
+keep our value and the merge is reported as failed, so they can be set
+again with 'envseal set' before 'git add'.
+
+When one side rotated a group key, or both changed the recipients of a
+group, values are re-encrypted and the key wrapped again, which needs
+your identity to open the groups involved.
+
+PATH (or --vault-name) gives the vault's real file name, which values are
+bound to.`,
+		Args:         cobra.RangeArgs(3, 4),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGitMerge(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().String("vault-name", "", "File name the vault is encrypted under (default: the base name of PATH)")
+	cmd.AddCommand(newGitMergeSetupCommand())
+	return cmd
+}
+
+func runGitMerge(cmd *cobra.Command, args []string, deps Deps) error {
+	basePath, oursPath, theirsPath := args[0], args[1], args[2]
+	path, _ := cmd.Flags().GetString("vault-name")
+	if path == "" && len(args) == 4 {
+		path = args[3]
+	}
+	if path == "" {
+		return errors.New("the vault's path is needed to decrypt it: pass PATH (%P) or --vault-name")
+	}
+	name := filepath.Base(path)
+
+	load := func(file string) (*config.SecretFile, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		sf, err := config.ParseSecretFile(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		return sf, nil
+	}
+	base, err := load(basePath)
+	if err != nil {
+		return err
+	}
+	ours, err := load(oursPath)
+	if err != nil {
+		return err
+	}
+	theirs, err := load(theirsPath)
+	if err != nil {
+		return err
+	}
+	defer ours.Lock()
+
+	report, err := ours.Merge(base, theirs, func() ([]age.Identity, error) {
+		return deps.IdentityManager.Load(identityFilePath)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot merge %s: %w", path, err)
+	}
+	if err := ours.SaveTo(oursPath); err != nil {
+		return fmt.Errorf("failed to write merged %s: %w", path, err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	cmd.PrintErrf("envseal: merged %s\n", path)
+	if len(report.Theirs) > 0 {
+		cmd.PrintErrf("  %s from theirs: %s\n", green("✓"), strings.Join(report.Theirs, ", "))
+	}
+	if len(report.Rewrapped) > 0 {
+		cmd.PrintErrf("  %s recipients merged in group(s): %s\n", green("✓"), strings.Join(report.Rewrapped, ", "))
+	}
+	if len(report.Reencrypted) > 0 {
+		cmd.PrintErrf("  %s re-encrypted: %s\n", green("✓"), strings.Join(report.Reencrypted, ", "))
+	}
+	if len(report.Conflicts) > 0 {
+		cmd.PrintErrf("  %s conflicting changes (both sides changed them, or the other side removed their group), kept ours: %s\n", red("✗"), strings.Join(report.Conflicts, ", "))
+		cmd.PrintErrf("  Set the right values with 'envseal set', then 'git add %s'.\n", path)
+		return errMergeConflicts
+	}
+	return nil
+}
+
+func newGitMergeSetupCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "setup",
+		Short: "Configure Git to merge vaults with envseal",
+		Long: fmt.Sprintf(`Marks *.enc.yaml files with "merge=%[1]s" in .gitattributes (to commit,
+so everyone gets it) and declares the %[1]s driver in the repository's
+Git config (local to each clone):
+
+  merge.%[1]s.driver = /path/to/envseal git-merge %%O %%A %%B %%P
+
+The driver runs this binary by its absolute path; run setup again after
+moving it.`, gitMergeDriver),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGitMergeSetup(cmd)
+		},
+	}
+}
+
+func runGitMergeSetup(cmd *cobra.Command) error {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return errors.New("not a git repository (or any of the parent directories)")
+	}
+
+	if err := gitRun("config", "merge."+gitMergeDriver+".name", "envseal vault merge"); err != nil {
+		return fmt.Errorf("failed to set git config: %w", err)
+	}
+	if err := gitRun("config", "merge."+gitMergeDriver+".driver", selfCommand("git-merge %O %A %B %P")); err != nil {
+		return fmt.Errorf("failed to set git config: %w", err)
+	}
+
+	changed, err := ensureGitAttribute(filepath.Join(root, ".gitattributes"), "*.enc.yaml", "merge", gitMergeDriver)
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	cmd.Printf("%s merge.%s.driver set in the Git config of this clone\n", green("✓"), gitMergeDriver)
+	if changed {
+		cmd.Printf("%s .gitattributes updated; commit it so everyone's merges use envseal\n", green("✓"))
+	} else {
+		cmd.Printf("%s .gitattributes already uses the %s merge driver\n", green("✓"), gitMergeDriver)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+// NewGroupsCommand creates the parent command for group management.
+func NewGroupsCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "groups",
+		Short: "List groups and manage their members",
+		Long: `Groups are named sets of users declared in envseal.yaml. Secrets set with
+'envseal set --group NAME' and environments listing a group are readable by
+its members only.
+
+Without a subcommand, lists the groups and their members.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGroupsList(cmd, deps)
+		},
+	}
+
+	cmd.AddCommand(newGroupsAddCommand(deps))
+	cmd.AddCommand(newGroupsRemoveCommand(deps))
+	return cmd
+}
+
+func runGroupsList(cmd *cobra.Command, deps Deps) error {
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	if len(manifest.Groups) == 0 {
+		cmd.Printf("No groups declared in %s.\n", config.ManifestFileName)
+		return nil
+	}
+	for _, g := range manifest.Groups {
+		members := strings.Join(g.Members, ", ")
+		if members == "" {
+			members = "(no members)"
+		}
+		cmd.Printf("%-15s %s\n", g.Name, members)
+	}
+	return nil
+}
+
+func newGroupsAddCommand(deps Deps) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <group> <alias>...",
+		Short: "Add users to a group, declaring it if needed",
+		Long: `Adds users to a group in envseal.yaml.
+
+As with 'users add', run 'envseal rekey' afterwards to give them the group's key.`,
+		Example: `  envseal groups add backend jane bob`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGroupsChange(cmd, deps, args, true)
+		},
+	}
+}
+
+func newGroupsRemoveCommand(deps Deps) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <group> <alias>...",
+		Short: "Remove users from a group",
+		Long: `Removes users from a group in envseal.yaml. The group stays declared.
+
+Run 'envseal rekey --rotate' afterwards to revoke their access to the group's secrets.`,
+		Example: `  envseal groups remove contractors jane`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGroupsChange(cmd, deps, args, false)
+		},
+	}
+}
+
+func runGroupsChange(cmd *cobra.Command, deps Deps, args []string, add bool) error {
+	group, names := args[0], args[1:]
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	if err := requireAdmin(deps, manifest); err != nil {
+		return err
+	}
+
+	declaringDefault := add && group == config.DefaultGroup && !slices.ContainsFunc(manifest.Groups, func(g config.Group) bool { return g.Name == group })
+	if add {
+		err = manifest.AddGroupMembers(group, names...)
+	} else {
+		err = manifest.RemoveGroupMembers(group, names...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update group %s: %w", group, err)
+	}
+
+	if err := deps.ManifestStore.Save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+	if add {
+		cmd.Printf("%s Added %s to group %s.\n", green("✓"), strings.Join(names, ", "), group)
+		if declaringDefault {
+			cmd.Println(yellow("⚠️  The default group is now declared: untagged secrets are limited to its members."))
+		}
+		cmd.Printf("Run %s to give them the group's key.\n", bold("envseal rekey"))
+	} else {
+		cmd.Printf("%s Removed %s from group %s.\n", green("✓"), strings.Join(names, ", "), group)
+		cmd.Printf("Run %s to revoke their access to its secrets.\n", bold("envseal rekey --rotate"))
+	}
+	return nil
+}
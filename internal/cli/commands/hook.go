@@ -1,14 +1,20 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/pkg/filesystem"
 )
 
 const hookMarkerStart = "# --- envseal pre-commit hook start ---"
@@ -17,38 +23,109 @@ const hookMarkerEnd = "# --- envseal pre-commit hook end ---"
 // preCommitPayload is the pure shell logic without the shebang
 const preCommitPayload = `
 if command -v envseal >/dev/null 2>&1; then
-    MANIFEST_STAGED=$(git diff --cached --name-only | grep "^envseal\.yaml$")
-    if [ -n "$MANIFEST_STAGED" ]; then
-        ALL_VAULTS=$(git ls-files | grep "\.enc\.yaml$")
-        for vault in $ALL_VAULTS; do
-            IS_STAGED=$(git diff --cached --name-only | grep "^$vault$")
-            if [ -z "$IS_STAGED" ]; then
-                echo "❌ envseal: Manifest (envseal.yaml) modified but vault ($vault) is not."
-                echo "   Did you forget to run 'envseal rekey --file $vault'?"
-                exit 1
-            fi
-        done
+    if git diff --cached --name-only | grep -q -e "^envseal\.yaml$" -e "\.enc\.yaml$"; then
+        envseal hook check || exit 1
     fi
 fi
 `
 
+var errHookCheckFailed = errors.New("vaults out of sync with the manifest")
+
+// shellSafeRe matches words a POSIX shell takes literally.
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+
 func NewHookCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "hook",
 		Short: "Manage Git integration hooks",
 	}
 	cmd.AddCommand(newHookInstallCommand())
+	cmd.AddCommand(newHookCheckCommand())
 	return cmd
 }
 
-func newHookInstallCommand() *cobra.Command {
+func newHookCheckCommand() *cobra.Command {
 	return &cobra.Command{
+		Use:   "check",
+		Short: "Check that staged vaults match the staged manifest",
+		Long: `Compares the recipients of every vault in the Git index with those the
+staged envseal.yaml gives it (per group and environment), without
+decrypting anything. Run by the pre-commit hook.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         runHookCheck,
+	}
+}
+
+func runHookCheck(cmd *cobra.Command, args []string) error {
+	data, err := exec.Command("git", "show", ":"+config.ManifestFileName).Output()
+	if err != nil {
+		// No staged manifest: nothing to compare against.
+		return nil
+	}
+	manifest, err := config.ParseManifest(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse staged %s: %w", config.ManifestFileName, err)
+	}
+
+	vaults, err := gitTrackedVaults()
+	if err != nil {
+		return fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	failed := false
+	for _, vault := range vaults {
+		data, err := exec.Command("git", "show", ":"+vault).Output()
+		if err != nil {
+			return fmt.Errorf("failed to read staged %s: %w", vault, err)
+		}
+		sf, err := config.ParseSecretFile(vault, data)
+		if err != nil {
+			return fmt.Errorf("failed to parse staged %s: %w", vault, err)
+		}
+		if vaultInSync(manifest, sf) {
+			continue
+		}
+
+		failed = true
+		cmd.PrintErrf("❌ envseal: %s does not match the access list of %s.\n", vault, config.ManifestFileName)
+		if env, ok := manifest.EnvironmentForFile(vault); ok {
+			cmd.PrintErrf("   Did you forget to run 'envseal rekey --env %s' and stage it?\n", env.Name)
+		} else {
+			cmd.PrintErrf("   Did you forget to run 'envseal rekey --file %s' and stage it?\n", vault)
+		}
+	}
+
+	if failed {
+		return errHookCheckFailed
+	}
+	return nil
+}
+
+func newHookInstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "install",
 		Short: "Install the pre-commit Git hook",
-		Long:  "Installs a Git pre-commit hook to prevent committing desynchronized files. Safely appends to existing hooks.",
-		Args:  cobra.NoArgs,
-		RunE:  runHookInstall,
+		Long: `Installs a Git pre-commit hook to prevent committing desynchronized files. Safely appends to existing hooks.
+
+With --drivers it also sets up the envseal diff and merge drivers for
+*.enc.yaml files (see 'envseal git-diff setup' and 'envseal git-merge setup').`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runHookInstall(cmd, args); err != nil {
+				return err
+			}
+			if drivers, _ := cmd.Flags().GetBool("drivers"); !drivers {
+				return nil
+			}
+			if err := runGitDiffSetup(cmd, false); err != nil {
+				return err
+			}
+			return runGitMergeSetup(cmd)
+		},
 	}
+	cmd.Flags().Bool("drivers", false, "Also set up the envseal diff and merge drivers for vaults")
+	return cmd
 }
 
 func runHookInstall(cmd *cobra.Command, args []string) error {
@@ -95,9 +172,25 @@ func runHookInstall(cmd *cobra.Command, args []string) error {
 
 	contentStr := string(content)
 
-	// Check if our block is already installed
-	if strings.Contains(contentStr, hookMarkerStart) {
-		cmd.Printf("%s envseal pre-commit hook is already installed in %s\n", green("✓"), hookPath)
+	// Check if our block is already installed, and bring it up to date
+	if start := strings.Index(contentStr, hookMarkerStart); start >= 0 {
+		end := strings.Index(contentStr[start:], hookMarkerEnd)
+		if end < 0 {
+			return fmt.Errorf("%s has an unterminated envseal block; remove it and run install again", hookPath)
+		}
+		end += start + len(hookMarkerEnd) + 1
+		if end > len(contentStr) {
+			end = len(contentStr)
+		}
+		if contentStr[start:end] == hookBlock {
+			cmd.Printf("%s envseal pre-commit hook is already installed in %s\n", green("✓"), hookPath)
+			return nil
+		}
+		updated := contentStr[:start] + hookBlock + contentStr[end:]
+		if err := os.WriteFile(hookPath, []byte(updated), 0755); err != nil {
+			return fmt.Errorf("failed to update pre-commit hook: %w", err)
+		}
+		cmd.Printf("%s envseal pre-commit hook updated in %s\n", green("✓"), hookPath)
 		return nil
 	}
 
@@ -131,3 +224,96 @@ func runHookInstall(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// gitOutput runs git with args and returns its trimmed standard output.
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func gitRun(args ...string) error {
+	_, err := gitOutput(args...)
+	return err
+}
+
+// selfCommand returns a shell command running this binary with args. It
+// uses the absolute path of the binary, as Git runs drivers with the shell
+// and envseal may be installed as envseal-cli or outside $PATH.
+func selfCommand(args string) string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "envseal " + args
+	}
+	return shellQuote(exe) + " " + args
+}
+
+// shellQuote quotes s for a POSIX shell, unless it needs no quoting.
+func shellQuote(s string) string {
+	if s != "" && shellSafeRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// gitTrackedVaults lists the *.enc.yaml files in the Git index, in and below
+// the current directory.
+func gitTrackedVaults() ([]string, error) {
+	out, err := exec.Command("git", "ls-files", "-z", "--", "*.enc.yaml").Output()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// ensureGitAttribute sets attr=value on the line of pattern in the
+// .gitattributes file at path, adding the line if needed, and reports
+// whether the file changed.
+func ensureGitAttribute(path, pattern, attr, value string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	want := attr + "=" + value
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+
+	found := false
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != pattern {
+			continue
+		}
+		found = true
+		kept := []string{pattern}
+		for _, f := range fields[1:] {
+			name, _, _ := strings.Cut(strings.TrimLeft(f, "-!"), "=")
+			if name != attr {
+				kept = append(kept, f)
+			}
+		}
+		if !slices.Contains(fields, want) || len(kept) != len(fields)-1 {
+			lines[i] = strings.Join(append(kept, want), " ")
+		}
+	}
+	if !found {
+		lines = append(lines, pattern+" "+want)
+	}
+
+	updated := strings.Join(lines, "\n") + "\n"
+	if updated == string(content) {
+		return false, nil
+	}
+	if err := filesystem.AtomicWriteFile(path, []byte(updated), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
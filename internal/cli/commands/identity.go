@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewIdentityCommand creates the parent command for local identity management.
+func NewIdentityCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Manage your local identity file",
+		Long:  `Protect or maintain the private key stored in your identity file.`,
+	}
+
+	cmd.AddCommand(newIdentityEncryptCommand(deps))
+	cmd.AddCommand(newIdentityRotateCommand(deps))
+	return cmd
+}
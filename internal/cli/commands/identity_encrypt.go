@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+	"github.com/flootic/envseal/pkg/filesystem"
+)
+
+func newIdentityEncryptCommand(deps Deps) *cobra.Command {
+	return &cobra.Command{
+		Use:   "encrypt",
+		Short: "Protect your identity file with a passphrase",
+		Long: fmt.Sprintf(`Encrypts the age secret key in your identity file with a passphrase (age
+scrypt). The public key does not change, so no rekey is needed.
+
+Every command that needs the identity then asks for the passphrase. For
+non-interactive use, set %s instead.
+
+SSH private keys are protected with 'ssh-keygen -p' instead.`, config.PassphraseEnvVar),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIdentityEncrypt(cmd)
+		},
+	}
+}
+
+func runIdentityEncrypt(cmd *cobra.Command) error {
+	path := identityFile(identityFilePath)
+	if path == "" {
+		return fmt.Errorf("identity source %s is not a file; protect the secret where it is stored", identityFilePath)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no identity at %s (run 'envseal-cli init' first)", path)
+		}
+		return fmt.Errorf("failed to read identity: %w", err)
+	}
+
+	if crypto.IsEncryptedIdentity(content) {
+		return errors.New("identity is already passphrase-protected")
+	}
+
+	privKey := strings.TrimSpace(string(content))
+	if strings.HasPrefix(privKey, "-----BEGIN") {
+		return fmt.Errorf("%s is an SSH key; protect it with: ssh-keygen -p -f %s", path, path)
+	}
+	if _, err := crypto.ParseIdentities(privKey); err != nil {
+		return fmt.Errorf("invalid identity file: %w", err)
+	}
+
+	pass, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := crypto.EncryptIdentity(privKey, pass)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt identity: %w", err)
+	}
+
+	if err := filesystem.AtomicWriteFile(path, encrypted, 0o600); err != nil {
+		return fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	cmd.Println(color.GreenString("✓ Identity at %s is now passphrase-protected.", path))
+	return nil
+}
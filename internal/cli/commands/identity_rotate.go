@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+	"github.com/flootic/envseal/pkg/filesystem"
+)
+
+// identityBackupSuffix names the copy of the previous identity file kept by
+// 'identity rotate' until the rotation is confirmed.
+const identityBackupSuffix = ".bak"
+
+func newIdentityRotateCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Replace your key in the manifest and every vault you can open",
+		Long: `Generates a new identity and switches every place that references your
+current public key to it, without an admin:
+- Updates your entry in envseal.yaml
+- Rewraps the DEK for the new key in every *.enc.yaml you can unlock
+- Keeps the previous identity file as <identity>.bak
+
+Once the changes are pushed and the new key works, run
+'envseal identity rotate --confirm' to delete the backup.
+
+The new key is post-quantum if the old one was (or with --pq), and
+passphrase-protected if the old one was (or with --passphrase).`,
+		Example: `  envseal identity rotate
+  git push
+  envseal identity rotate --confirm`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			confirm, err := cmd.Flags().GetBool("confirm")
+			if err != nil {
+				return err
+			}
+			if confirm {
+				return runIdentityRotateConfirm(cmd, deps)
+			}
+			return runIdentityRotate(cmd, deps)
+		},
+	}
+
+	cmd.Flags().Bool("pq", false, "Generate a post-quantum hybrid (ML-KEM-768 + X25519) identity")
+	cmd.Flags().Bool("passphrase", false, "Protect the new identity with a passphrase")
+	cmd.Flags().Bool("confirm", false, "Check that the new key opens your vaults, then delete the backup of the old one")
+	return cmd
+}
+
+func runIdentityRotate(cmd *cobra.Command, deps Deps) error {
+	postQuantum, err := cmd.Flags().GetBool("pq")
+	if err != nil {
+		return err
+	}
+	passphrase, err := cmd.Flags().GetBool("passphrase")
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	path := identityFile(identityFilePath)
+	if path == "" {
+		return fmt.Errorf("identity source %s is not a file; rotate the key where it is stored", identityFilePath)
+	}
+	backupPath := path + identityBackupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		return fmt.Errorf("a previous rotation is awaiting confirmation (%s exists); run 'envseal identity rotate --confirm' first", backupPath)
+	}
+
+	oldContent, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read identity: %w", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(oldContent)), "-----BEGIN") {
+		return fmt.Errorf("%s is an SSH key; generate a new SSH key and ask an admin to replace it", path)
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+	oldPubKey, err := crypto.IdentityRecipient(identities[0])
+	if err != nil {
+		return err
+	}
+	if crypto.IsPluginRecipient(oldPubKey) {
+		return errors.New("plugin identities are rotated with their plugin; ask an admin to replace the public key")
+	}
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	user, ok := manifest.FindUserByPublicKey(oldPubKey)
+	if !ok {
+		return fmt.Errorf("your public key is not in %s", config.ManifestFileName)
+	}
+
+	privKey, newPubKey, err := deps.IdentityManager.Generate(postQuantum || crypto.IsPostQuantumRecipient(oldPubKey))
+	if err != nil {
+		return fmt.Errorf("failed to generate identity: %w", err)
+	}
+	newIdentities, err := crypto.ParseIdentities(privKey)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.ReplacePublicKey(oldPubKey, newPubKey); err != nil {
+		return fmt.Errorf("failed to update manifest: %w", err)
+	}
+
+	cmd.Printf("🔄 Rotating key of %s\n", cyan(user.Name))
+
+	// Rewrap every vault in memory first so nothing is written if one fails.
+	var rewrapped []*config.SecretFile
+	var skipped []string
+	for _, vaultPath := range projectVaultPaths(manifest) {
+		sf, err := deps.SecretsStore.Load(vaultPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				skipped = append(skipped, fmt.Sprintf("%s (%v)", vaultPath, err))
+			}
+			continue
+		}
+
+		recipients, err := sf.GetRecipients()
+		if err != nil || !slices.Contains(recipients, oldPubKey) {
+			continue
+		}
+		if _, err := sf.Unlock(identities...); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", vaultPath, err))
+			continue
+		}
+		defer sf.Lock()
+
+		for _, group := range sf.Groups() {
+			members, err := sf.GroupRecipients(group)
+			if err != nil {
+				return err
+			}
+			i := slices.Index(members, oldPubKey)
+			if i < 0 {
+				continue
+			}
+			members[i] = newPubKey
+			if err := sf.RotateGroupRecipients(group, members); err != nil {
+				return fmt.Errorf("failed to rewrap %s (group %s): %w", vaultPath, group, err)
+			}
+		}
+		if _, err := sf.Unlock(newIdentities...); err != nil {
+			return fmt.Errorf("new key cannot open %s: %w", vaultPath, err)
+		}
+		rewrapped = append(rewrapped, sf)
+	}
+
+	content := []byte(privKey)
+	if passphrase || crypto.IsEncryptedIdentity(oldContent) {
+		pass, err := readNewPassphrase()
+		if err != nil {
+			return err
+		}
+		if content, err = crypto.EncryptIdentity(privKey, pass); err != nil {
+			return fmt.Errorf("failed to encrypt identity: %w", err)
+		}
+	}
+
+	if err := filesystem.AtomicWriteFile(backupPath, oldContent, 0o600); err != nil {
+		return fmt.Errorf("failed to back up old identity: %w", err)
+	}
+	if err := filesystem.AtomicWriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("failed to save new identity (old one kept at %s): %w", backupPath, err)
+	}
+	cmd.Println(green("✓ New identity saved to " + path))
+	cmd.Println(green("✓ Old identity kept at " + backupPath))
+
+	gitFiles := []string{config.ManifestFileName}
+	for _, sf := range rewrapped {
+		if err := sf.Save(); err != nil {
+			return fmt.Errorf("failed to save %s: %w", sf.Path(), err)
+		}
+		cmd.Println(green("✓ Rewrapped " + sf.Path()))
+		gitFiles = append(gitFiles, sf.Path())
+	}
+
+	if err := deps.ManifestStore.Save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	cmd.Println(green("✓ " + config.ManifestFileName + " updated"))
+
+	for _, s := range skipped {
+		cmd.Printf("%s skipped %s\n", yellow("•"), s)
+	}
+
+	cmd.Printf("\n%s Key rotated.\n", bold("SUCCESS:"))
+	cmd.Println("Commit and push the changes to Git:")
+	cmd.Println(cyan("  git add " + strings.Join(gitFiles, " ")))
+	cmd.Println(cyan(fmt.Sprintf(`  git commit -m "Rotate key of %s"`, user.Name)))
+	cmd.Println("Then run", bold("envseal identity rotate --confirm"), "to delete the old key.")
+	cmd.Println(yellow("If the old key may be compromised, also run 'envseal rekey --rotate' on each vault."))
+
+	return nil
+}
+
+func runIdentityRotateConfirm(cmd *cobra.Command, deps Deps) error {
+	path := identityFile(identityFilePath)
+	if path == "" {
+		return fmt.Errorf("identity source %s is not a file", identityFilePath)
+	}
+	backupPath := path + identityBackupSuffix
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("no rotation awaiting confirmation (%s not found)", backupPath)
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error: %w", err)
+	}
+	pubKey, err := crypto.IdentityRecipient(identities[0])
+	if err != nil {
+		return err
+	}
+
+	// Every vault listing the new key must open with it before the old key goes.
+	manifest, _ := deps.ManifestStore.Load()
+	for _, vaultPath := range projectVaultPaths(manifest) {
+		if err := checkVaultOpens(deps, vaultPath, pubKey, identities); err != nil {
+			return fmt.Errorf("%w; keeping %s", err, backupPath)
+		}
+	}
+
+	if err := os.Remove(backupPath); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", backupPath, err)
+	}
+
+	cmd.Println(color.GreenString("✓ New key verified; old identity %s deleted.", backupPath))
+	return nil
+}
+
+func checkVaultOpens(deps Deps, vaultPath, pubKey string, identities []age.Identity) error {
+	sf, err := deps.SecretsStore.Load(vaultPath)
+	if err != nil {
+		return nil
+	}
+	recipients, err := sf.GetRecipients()
+	if err != nil || !slices.Contains(recipients, pubKey) {
+		return nil
+	}
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("new key cannot open %s: %w", vaultPath, err)
+	}
+	sf.Lock()
+	return nil
+}
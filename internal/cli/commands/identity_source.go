@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// Identity sources accepted by --identity and $ENVSEAL_IDENTITY_SOURCE.
+// Anything without one of these prefixes is a plain file path.
+const (
+	identitySourceEnv  = "env:"
+	identitySourceFD   = "fd:"
+	identitySourceCmd  = "cmd:"
+	identitySourceFile = "file:"
+
+	// identitySourceRecoveryWords is set by --recovery-words: the identity is
+	// the recovery key, rebuilt from its word list.
+	identitySourceRecoveryWords = "recovery-words:"
+)
+
+// identitySourceCache keeps what non-file sources returned, since a file
+// descriptor can only be read once and commands should not run twice.
+var identitySourceCache sync.Map
+
+// identityCache keeps the identities parsed from each identity content, so
+// a passphrase-protected key is decrypted, and its passphrase asked for,
+// once per process. It is keyed by content: a file rewritten meanwhile is
+// parsed again.
+var identityCache sync.Map
+
+// identityFile returns the path behind a file identity source, or "" when
+// the identity does not live in a file.
+func identityFile(source string) string {
+	if path, ok := strings.CutPrefix(source, identitySourceFile); ok {
+		return path
+	}
+	for _, prefix := range []string{identitySourceEnv, identitySourceFD, identitySourceCmd, identitySourceRecoveryWords} {
+		if strings.HasPrefix(source, prefix) {
+			return ""
+		}
+	}
+	return source
+}
+
+// readIdentitySource returns the identity content held by a non-file source.
+// The content stays in memory and is never written to disk.
+func readIdentitySource(source string) ([]byte, error) {
+	if cached, ok := identitySourceCache.Load(source); ok {
+		return cached.([]byte), nil
+	}
+
+	var content []byte
+	switch {
+	case strings.HasPrefix(source, identitySourceEnv):
+		name := strings.TrimPrefix(source, identitySourceEnv)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		content = []byte(v)
+
+	case strings.HasPrefix(source, identitySourceFD):
+		fd, err := strconv.Atoi(strings.TrimPrefix(source, identitySourceFD))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid file descriptor in %q", source)
+		}
+		f := os.NewFile(uintptr(fd), source)
+		if f == nil {
+			return nil, fmt.Errorf("invalid file descriptor in %q", source)
+		}
+		content, err = io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+	case strings.HasPrefix(source, identitySourceCmd):
+		out, err := runIdentityCommand(strings.TrimPrefix(source, identitySourceCmd))
+		if err != nil {
+			return nil, err
+		}
+		content = out
+
+	case source == identitySourceRecoveryWords:
+		words, ok := os.LookupEnv(config.RecoveryWordsEnvVar)
+		if !ok {
+			typed, err := readPassphrase("Enter the recovery words (on one line): ")
+			if err != nil {
+				return nil, err
+			}
+			words = string(typed)
+		}
+		identity, err := crypto.ParseRecoveryWords(words)
+		if err != nil {
+			return nil, err
+		}
+		content = []byte(identity.String())
+
+	default:
+		return nil, fmt.Errorf("unknown identity source %q", source)
+	}
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("identity source %s is empty", source)
+	}
+
+	identitySourceCache.Store(source, content)
+	return content, nil
+}
+
+// parseIdentitySource parses the identities held by content, which came
+// from name. path is the file it was read from, "" for other sources.
+func parseIdentitySource(name, path string, content []byte) ([]age.Identity, error) {
+	sum := sha256.Sum256(content)
+	key := path + "\x00" + string(sum[:])
+	if cached, ok := identityCache.Load(key); ok {
+		return slices.Clone(cached.([]age.Identity)), nil
+	}
+
+	identities, err := crypto.ParseIdentityFile(path, content, identityPassphrase(name))
+	if err != nil {
+		return nil, err
+	}
+	identityCache.Store(key, identities)
+	return slices.Clone(identities), nil
+}
+
+// runIdentityCommand runs command through the shell and returns its stdout.
+// stdin and stderr stay attached so tools like 'pass' can prompt.
+func runIdentityCommand(command string) ([]byte, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("empty command in %q", identitySourceCmd)
+	}
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Stdin = os.Stdin
+	c.Stderr = os.Stderr
+
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("identity command failed: %w", err)
+	}
+	return out, nil
+}
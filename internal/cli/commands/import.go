@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/envfile"
+	"github.com/flootic/envseal/pkg/filesystem"
+)
+
+// importChange is what 'import' does with one variable.
+type importChange int
+
+const (
+	importAdded importChange = iota
+	importChanged
+	importUnchanged
+	importSkipped
+	importConflict
+)
+
+func NewImportCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Encrypt the variables of a .env, JSON, YAML or shell file into the vault",
+		Long: fmt.Sprintf(`Reads variables from a plaintext file and stores them as secrets.
+
+Formats (--format, guessed from the extension by default):
+  dotenv  KEY=value lines: comments, "export " prefixes, 'literal' and
+          "escaped" (\n, \t, \", \\) values, quoted values spanning lines
+  shell   export KEY='value' lines with POSIX quoting, as written by
+          'export -p' or 'envseal export --format shell'
+  json    an object of KEY: value
+  yaml    a map of KEY: value
+
+Keys already in the vault with a different value are conflicts: the import
+is refused unless --overwrite replaces them or --skip-existing keeps the
+vault's value. Keys with the same value are left untouched. Values are never
+printed; --dry-run shows which keys would be added, changed or kept.
+
+With --shred the source file is overwritten and deleted once the vault is
+saved. This is best effort: copy-on-write filesystems, SSDs and backups may
+keep copies of the plaintext. Use "-" to read from stdin.
+
+Formats: %s.`, strings.Join(envfile.ImportFormats, ", ")),
+		Example: `  envseal import .env --dry-run
+  envseal import .env --shred
+  envseal import config.json --skip-existing
+  heroku config --shell | envseal import - --format shell --overwrite`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().String("format", formatAuto, "Input format: auto, "+strings.Join(envfile.ImportFormats, ", "))
+	cmd.Flags().Bool("dry-run", false, "Show what would change without writing the vault")
+	cmd.Flags().Bool("overwrite", false, "Replace secrets that already exist with a different value")
+	cmd.Flags().Bool("skip-existing", false, "Keep secrets that already exist and import only new keys")
+	cmd.Flags().String("group", "", "Group whose members can read the imported secrets (see groups in envseal.yaml)")
+	cmd.Flags().Bool("shred", false, "Overwrite and delete the source file after a successful import")
+	cmd.MarkFlagsMutuallyExclusive("overwrite", "skip-existing")
+	addNoStrictFlag(cmd)
+	return cmd
+}
+
+func runImport(cmd *cobra.Command, args []string, deps Deps) error {
+	format, _ := cmd.Flags().GetString("format")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	group, _ := cmd.Flags().GetString("group")
+	shred, _ := cmd.Flags().GetBool("shred")
+
+	path := args[0]
+	if shred && path == "-" {
+		return errors.New("--shred needs a file, not stdin")
+	}
+	if format == formatAuto {
+		format = envfile.DetectFormat(path)
+	}
+
+	data, err := readImportSource(cmd, path)
+	if err != nil {
+		return err
+	}
+	defer clear(data)
+
+	vars, err := envfile.Parse(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s as %s: %w", path, format, err)
+	}
+	if len(vars) == 0 {
+		return fmt.Errorf("no variables found in %s", path)
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+	defer sf.Lock()
+
+	current, err := readAllSecrets(cmd, sf)
+	if err != nil {
+		return err
+	}
+	existing := sf.SecretKeys()
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	counts := make(map[importChange]int)
+	var conflicts []string
+	changes := make([]importChange, len(vars))
+	for i, v := range vars {
+		old, readable := current[v.Key]
+		switch {
+		case !slices.Contains(existing, v.Key):
+			changes[i] = importAdded
+		case readable && old == v.Value:
+			changes[i] = importUnchanged
+		case overwrite:
+			changes[i] = importChanged
+		case skipExisting:
+			changes[i] = importSkipped
+		default:
+			changes[i] = importConflict
+			conflicts = append(conflicts, v.Key)
+		}
+		counts[changes[i]]++
+	}
+
+	cmd.Printf("📥 Importing %s (%s) into %s\n", cyan(path), format, cyan(secretFilePath))
+	for i, v := range vars {
+		switch changes[i] {
+		case importAdded:
+			cmd.Printf("  %s %s\n", green("+"), v.Key)
+		case importChanged:
+			cmd.Printf("  %s %s (overwritten)\n", yellow("~"), v.Key)
+		case importUnchanged:
+			cmd.Printf("  = %s (unchanged)\n", v.Key)
+		case importSkipped:
+			cmd.Printf("  - %s (exists, skipped)\n", v.Key)
+		case importConflict:
+			cmd.Printf("  %s %s (exists with a different value)\n", red("!"), v.Key)
+		}
+	}
+	cmd.Printf("%d new, %d overwritten, %d unchanged, %d skipped\n",
+		counts[importAdded], counts[importChanged], counts[importUnchanged], counts[importSkipped])
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d key(s) already exist with a different value: %s (use --overwrite or --skip-existing)",
+			len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	if dryRun {
+		cmd.Println(yellow("Dry run: nothing written."))
+		return nil
+	}
+
+	if counts[importAdded]+counts[importChanged] > 0 {
+		if group != "" && !sf.HasGroup(group) {
+			if err := createVaultGroup(cmd, manifest, sf, group); err != nil {
+				return err
+			}
+		}
+
+		author := authorName(manifest, identities)
+		now := time.Now().UTC().Truncate(time.Second)
+		for i, v := range vars {
+			if changes[i] != importAdded && changes[i] != importChanged {
+				continue
+			}
+			if err := sf.SetSecretInGroup(v.Key, v.Value, group); err != nil {
+				return fmt.Errorf("failed to set %s: %w", v.Key, err)
+			}
+			meta, _, err := sf.SecretMeta(v.Key)
+			if err != nil {
+				return fmt.Errorf("failed to read metadata of %s: %w", v.Key, err)
+			}
+			before := meta
+			touchSecretMeta(manifest, &meta, author, now)
+			if !meta.Equal(before) {
+				if err := sf.SetSecretMeta(v.Key, meta, manifest.EncryptSecretMetadata); err != nil {
+					return fmt.Errorf("failed to set metadata of %s: %w", v.Key, err)
+				}
+			}
+		}
+
+		if err := sf.Save(); err != nil {
+			return fmt.Errorf("failed to save %s: %w", secretFilePath, err)
+		}
+		cmd.Println(green("✓ Updated " + secretFilePath))
+	} else {
+		cmd.Println("Nothing to import.")
+	}
+
+	if shred {
+		if err := filesystem.SecureRemove(path); err != nil {
+			return fmt.Errorf("secrets imported, but failed to shred %s: %w", path, err)
+		}
+		cmd.Println(green("✓ Shredded " + path))
+	} else if path != "-" {
+		cmd.Println(yellow(fmt.Sprintf("⚠️  %s still holds the plaintext; delete it (or re-run with --shred) once you have checked the import.", path)))
+	}
+	return nil
+}
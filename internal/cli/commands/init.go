@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
 	"github.com/flootic/envseal/pkg/filesystem"
 )
 
@@ -20,7 +21,8 @@ func NewInitCommand(deps Deps) *cobra.Command {
 		Use:   "init",
 		Short: "Initialize EnvSeal in the current directory",
 		Long: `Initializes the current directory for EnvSeal:
-- Ensures your local identity exists
+- Ensures your local identity exists (post-quantum hybrid with --pq,
+  passphrase-protected with --passphrase)
 - Creates envseal.yaml (manifest)
 - Creates secrets.enc.yaml (encrypted secrets file)`,
 		Args: cobra.NoArgs,
@@ -30,6 +32,8 @@ func NewInitCommand(deps Deps) *cobra.Command {
 	}
 
 	cmd.Flags().String("name", "", "Project name for envseal.yaml (default: current directory name)")
+	cmd.Flags().Bool("pq", false, "Generate a post-quantum hybrid (ML-KEM-768 + X25519) identity if none exists")
+	cmd.Flags().Bool("passphrase", false, "Protect a newly created identity with a passphrase")
 	return cmd
 }
 
@@ -40,7 +44,19 @@ func runInit(cmd *cobra.Command, deps Deps) error {
 
 	cmd.Println("🚀 Initializing EnvSeal...")
 
-	pubKey, createdIdentity, err := ensureIdentity(cmd, deps)
+	postQuantum, err := cmd.Flags().GetBool("pq")
+	if err != nil {
+		return err
+	}
+	passphrase, err := cmd.Flags().GetBool("passphrase")
+	if err != nil {
+		return err
+	}
+
+	pubKey, createdIdentity, err := ensureIdentity(cmd, deps, newIdentityOptions{
+		postQuantum: postQuantum,
+		passphrase:  passphrase,
+	})
 	if err != nil {
 		return err
 	}
@@ -83,35 +99,64 @@ func runInit(cmd *cobra.Command, deps Deps) error {
 	return nil
 }
 
-func ensureIdentity(cmd *cobra.Command, deps Deps) (pubKey string, created bool, err error) {
-	if _, err := os.Stat(identityFilePath); err == nil {
-		id, err := deps.IdentityManager.Load(identityFilePath)
-		if err != nil {
-			return "", false, fmt.Errorf("failed to read identity: %w", err)
-		}
-		return id.Recipient().String(), false, nil
+// newIdentityOptions controls how ensureIdentity creates a missing identity.
+type newIdentityOptions struct {
+	postQuantum bool
+	passphrase  bool
+}
+
+func ensureIdentity(cmd *cobra.Command, deps Deps, opts newIdentityOptions) (pubKey string, created bool, err error) {
+	// env:, fd: and cmd: sources are read-only: nothing is generated into them.
+	path := identityFile(identityFilePath)
+	if path == "" {
+		pubKey, err := loadIdentityRecipient(deps)
+		return pubKey, false, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		pubKey, err := loadIdentityRecipient(deps)
+		return pubKey, false, err
 	} else if !os.IsNotExist(err) {
 		return "", false, fmt.Errorf("failed to stat identity: %w", err)
 	}
 
 	// Identity missing -> create.
-	if err := os.MkdirAll(filepath.Dir(identityFilePath), 0o700); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return "", false, fmt.Errorf("failed to create identity directory: %w", err)
 	}
 
 	// Use deps so it is testable.
-	priv, pub, err := deps.IdentityManager.Generate()
+	priv, pub, err := deps.IdentityManager.Generate(opts.postQuantum)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to generate identity: %w", err)
 	}
 
-	if err := filesystem.AtomicWriteFile(identityFilePath, []byte(priv), 0o600); err != nil {
+	content := []byte(priv)
+	if opts.passphrase {
+		pass, err := readNewPassphrase()
+		if err != nil {
+			return "", false, err
+		}
+		if content, err = crypto.EncryptIdentity(priv, pass); err != nil {
+			return "", false, fmt.Errorf("failed to encrypt identity: %w", err)
+		}
+	}
+
+	if err := filesystem.AtomicWriteFile(path, content, 0o600); err != nil {
 		return "", false, fmt.Errorf("failed to save identity: %w", err)
 	}
 
 	return pub, true, nil
 }
 
+func loadIdentityRecipient(deps Deps) (string, error) {
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity: %w", err)
+	}
+	return crypto.IdentityRecipient(identities[0])
+}
+
 func resolveProjectName(cmd *cobra.Command) (string, error) {
 	name, err := cmd.Flags().GetString("name")
 	if err != nil {
@@ -151,7 +196,7 @@ func ensureManifest(cmd *cobra.Command, deps Deps, projectName, pubKey string) (
 	m := &config.Manifest{
 		ProjectName: projectName,
 		AccessControl: []config.User{
-			{Name: userName, PublicKey: pubKey},
+			{Name: userName, PublicKey: pubKey, Role: config.RoleAdmin},
 		},
 	}
 
@@ -25,7 +25,7 @@ func NewJoinCommand(deps Deps) *cobra.Command {
 }
 
 func runJoin(cmd *cobra.Command, deps Deps) error {
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
@@ -35,7 +35,11 @@ func runJoin(cmd *cobra.Command, deps Deps) error {
 		return fmt.Errorf("failed to generate join code: %w", err)
 	}
 
-	pubKey := identity.Recipient().String()
+	// Only the primary identity is announced.
+	pubKey, err := crypto.IdentityRecipient(identities[0])
+	if err != nil {
+		return err
+	}
 
 	// Start broadcast and TCP listener
 	session, err := p2p.BroadcastKey(code, pubKey)
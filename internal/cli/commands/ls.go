@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+func NewLsCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List secrets with their metadata",
+		Long: `Lists the secrets of the vault with their group, owner, last update and
+description, without decrypting any value. Secrets past their rotation
+interval or expiry are flagged.
+
+Encrypted metadata (encrypt_secret_metadata in envseal.yaml) is shown only
+for the groups your identity can open.`,
+		Example: `  envseal ls
+  envseal ls --env prod`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLs(cmd, deps)
+		},
+	}
+	return cmd
+}
+
+func runLs(cmd *cobra.Command, deps Deps) error {
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	// Only needed for encrypted metadata, so a missing identity is fine.
+	if identities, err := deps.IdentityManager.Load(identityFilePath); err == nil {
+		if _, err := sf.Unlock(identities...); err == nil {
+			defer sf.Lock()
+		}
+	}
+
+	keys := sf.SecretKeys()
+	if len(keys) == 0 {
+		cmd.Printf("No secrets in %s.\n", secretFilePath)
+		return nil
+	}
+
+	groups := sf.SecretGroups()
+	now := time.Now()
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tGROUP\tOWNER\tUPDATED\tDESCRIPTION\tNOTES")
+	for _, k := range keys {
+		meta, _, err := sf.SecretMeta(k)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t%s\n", k, groups[k], "metadata locked")
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			k, groups[k], dashIfEmpty(meta.Owner), formatUpdated(meta), dashIfEmpty(meta.Description),
+			strings.Join(secretMetaNotes(meta, now), ", "))
+	}
+	return w.Flush()
+}
+
+// secretMetaNotes describes what needs attention about a secret.
+func secretMetaNotes(meta config.SecretMeta, now time.Time) []string {
+	var notes []string
+	if meta.Expired(now) {
+		notes = append(notes, "expired "+meta.ExpiresAt)
+	}
+	if due, ok := meta.RotationDue(); ok {
+		if meta.RotationOverdue(now) {
+			notes = append(notes, "rotation overdue since "+due.Format(time.DateOnly))
+		} else {
+			notes = append(notes, "rotate by "+due.Format(time.DateOnly))
+		}
+	}
+	return notes
+}
+
+func formatUpdated(meta config.SecretMeta) string {
+	if meta.UpdatedAt.IsZero() {
+		return "-"
+	}
+	s := meta.UpdatedAt.Format(time.DateOnly)
+	if meta.UpdatedBy != "" {
+		s += " by " + meta.UpdatedBy
+	}
+	return s
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
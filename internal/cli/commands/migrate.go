@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+func NewMigrateCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the vault to the current file format",
+		Long: fmt.Sprintf(`Upgrades the encrypted file in place to format version %d:
+- Moves legacy top-level entries under the secrets: map (encrypting plaintext ones)
+- Re-encrypts values still stored in the legacy v1 encoding
+- Records the format version in the _envseal block
+
+Every change is listed so it can be reviewed in a single commit.`, config.FormatVersion),
+		Example: `  envseal migrate --dry-run
+  envseal migrate -f secrets.prod.enc.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(cmd, deps)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Show what would change without writing the file")
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, deps Deps) error {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+	defer sf.Lock()
+
+	report, err := sf.Migrate()
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if !report.Changed() {
+		cmd.Println(green(fmt.Sprintf("✓ %s is already at format version %d. Nothing to do.", secretFilePath, report.ToVersion)))
+		return nil
+	}
+
+	cmd.Printf("🔧 Migrating %s\n", cyan(secretFilePath))
+	if report.FromVersion != report.ToVersion {
+		cmd.Printf("  • format version %d → %d\n", report.FromVersion, report.ToVersion)
+	}
+	printMigrationKeys(cmd, "moved into the secrets map", report.Moved)
+	printMigrationKeys(cmd, "encrypted (were stored as plaintext)", report.Encrypted)
+	printMigrationKeys(cmd, "removed (shadowed by an entry in the secrets map)", report.Dropped)
+	printMigrationKeys(cmd, "re-encrypted from v1 to v2 encoding", report.Upgraded)
+
+	if dryRun {
+		cmd.Println()
+		cmd.Println(yellow("Dry run: no changes written."))
+		return nil
+	}
+
+	if err := sf.Save(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", secretFilePath, err)
+	}
+
+	cmd.Printf("\n%s File migrated successfully.\n", bold("SUCCESS:"))
+	cmd.Println("Review and commit the changes to Git:")
+	cmd.Println(cyan("  git add " + secretFilePath))
+	cmd.Println(cyan(fmt.Sprintf(`  git commit -m "Migrate %s to format v%d"`, secretFilePath, report.ToVersion)))
+
+	return nil
+}
+
+func printMigrationKeys(cmd *cobra.Command, label string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	cmd.Printf("  • %d %s: %s\n", len(keys), label, strings.Join(keys, ", "))
+}
@@ -11,17 +11,22 @@ func NewPrintCommand(deps Deps) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "print",
 		Short: "Show decrypted variables",
-		Long:  "Decrypts the secrets file using your local identity and prints KEY=VALUE lines to stdout.",
-		Args:  cobra.NoArgs,
+		Long: `Decrypts the secrets file using your local identity and prints KEY=VALUE lines to stdout.
+
+Values are printed as-is, without quoting; use 'envseal export' to write a
+file that other tools can parse.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runPrint(cmd, deps)
 		},
 	}
+
+	addNoStrictFlag(cmd)
 	return cmd
 }
 
 func runPrint(cmd *cobra.Command, deps Deps) error {
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
@@ -31,14 +36,14 @@ func runPrint(cmd *cobra.Command, deps Deps) error {
 		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
 	}
 
-	if err := sf.Unlock(identity); err != nil {
+	if _, err := sf.Unlock(identities...); err != nil {
 		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
 	}
 	defer sf.Lock()
 
-	plain, err := sf.GetAllSecrets()
+	plain, err := readAllSecrets(cmd, sf)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt secrets: %w", err)
+		return err
 	}
 
 	keys := make([]string, 0, len(plain))
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// identityPassphrase returns the passphrase source for the identity at path:
+// $ENVSEAL_PASSPHRASE when set, an interactive prompt otherwise.
+func identityPassphrase(path string) crypto.PassphraseFunc {
+	return func() ([]byte, error) {
+		if v, ok := os.LookupEnv(config.PassphraseEnvVar); ok {
+			return []byte(v), nil
+		}
+		return readPassphrase(fmt.Sprintf("Enter passphrase for %s: ", path))
+	}
+}
+
+// readNewPassphrase asks for a new passphrase and its confirmation, or takes
+// it from $ENVSEAL_PASSPHRASE when set.
+func readNewPassphrase() ([]byte, error) {
+	if v, ok := os.LookupEnv(config.PassphraseEnvVar); ok {
+		if v == "" {
+			return nil, fmt.Errorf("%s is set but empty", config.PassphraseEnvVar)
+		}
+		return []byte(v), nil
+	}
+
+	pass, err := readPassphrase("Enter new passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	confirm, err := readPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pass, confirm) {
+		return nil, errors.New("passphrases do not match")
+	}
+
+	return pass, nil
+}
+
+// readPassphrase prompts for a secret on the controlling terminal with echo
+// disabled. The terminal is opened directly so prompting still works when
+// stdin/stdout are redirected (e.g. under 'envseal exec').
+func readPassphrase(prompt string) ([]byte, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		fmt.Fprint(tty, prompt)
+		pass, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		return pass, err
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no terminal available to prompt on")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return pass, err
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewRecoveryCommand creates the parent command for break-glass recovery.
+func NewRecoveryCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recovery",
+		Short: "Set up and use threshold (Shamir) recovery of a vault",
+		Long: `Splits the vault key into Shamir shares held by named custodians, so that
+the vault can be recovered if every recipient loses their key: any
+threshold of custodians together can grant access again.`,
+	}
+
+	cmd.AddCommand(newRecoverySetupCommand(deps))
+	cmd.AddCommand(newRecoveryShareCommand(deps))
+	cmd.AddCommand(newRecoveryUnlockCommand(deps))
+	return cmd
+}
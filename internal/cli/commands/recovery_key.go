@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewRecoveryKeyCommand creates the parent command for the offline recovery key.
+func NewRecoveryKeyCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recovery-key",
+		Short: "Manage the offline recovery key of the project",
+		Long: `The recovery key is an identity kept on paper (e.g. in a safe) as a list of
+words. It is a recipient of every vault, so the vaults can be opened with
+'--recovery-words' if every other key is lost.`,
+	}
+
+	cmd.AddCommand(newRecoveryKeyGenerateCommand(deps))
+	return cmd
+}
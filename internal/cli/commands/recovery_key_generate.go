@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// recoveryWordsPerLine is how many numbered words are printed per line.
+const recoveryWordsPerLine = 4
+
+func newRecoveryKeyGenerateCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Create the recovery key and add it to every vault",
+		Long: `Generates a dedicated age identity, records its public key as recovery_key
+in envseal.yaml and adds it as a recipient of every *.enc.yaml you can
+unlock. Later rekeys keep it.
+
+The private key is printed once, to stdout, as a list of words ending with
+a checksum. It is not stored anywhere: write it down and keep it safe.
+Use it with '--recovery-words' on any command:
+
+  envseal --recovery-words print`,
+		Example: `  envseal recovery-key generate
+  envseal recovery-key generate --force   # replace the current recovery key`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRecoveryKeyGenerate(cmd, deps)
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "Replace the existing recovery key")
+	return cmd
+}
+
+func runRecoveryKeyGenerate(cmd *cobra.Command, deps Deps) error {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	oldKey := manifest.RecoveryKey
+	if oldKey != "" && !force {
+		return fmt.Errorf("%s already has a recovery key (use --force to replace it)", config.ManifestFileName)
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	words, pubKey, err := crypto.GenerateRecoveryKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate recovery key: %w", err)
+	}
+
+	// Rewrap every vault in memory first so nothing is written if one fails.
+	var updated []*config.SecretFile
+	var skipped []string
+	for _, vaultPath := range projectVaultPaths(manifest) {
+		sf, err := deps.SecretsStore.Load(vaultPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				skipped = append(skipped, fmt.Sprintf("%s (%v)", vaultPath, err))
+			}
+			continue
+		}
+		if _, err := sf.Unlock(identities...); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", vaultPath, err))
+			continue
+		}
+		defer sf.Lock()
+
+		for _, group := range sf.Groups() {
+			if !sf.CanOpenGroup(group) {
+				skipped = append(skipped, fmt.Sprintf("%s group %s (not a member)", vaultPath, group))
+				continue
+			}
+			recipients, err := sf.GroupRecipients(group)
+			if err != nil {
+				return err
+			}
+			recipients = slices.DeleteFunc(recipients, func(r string) bool { return r == oldKey })
+			if err := sf.RotateGroupRecipients(group, append(recipients, pubKey)); err != nil {
+				return fmt.Errorf("failed to add the recovery key to %s (group %s): %w", vaultPath, group, err)
+			}
+		}
+		updated = append(updated, sf)
+	}
+
+	gitFiles := []string{config.ManifestFileName}
+	for _, sf := range updated {
+		if err := sf.Save(); err != nil {
+			return fmt.Errorf("failed to save %s: %w", sf.Path(), err)
+		}
+		cmd.Println(green("✓ Recovery key added to " + sf.Path()))
+		gitFiles = append(gitFiles, sf.Path())
+	}
+
+	manifest.RecoveryKey = pubKey
+	if err := deps.ManifestStore.Save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	cmd.Println(green("✓ " + config.ManifestFileName + " updated"))
+
+	for _, s := range skipped {
+		cmd.Printf("%s skipped %s (run 'envseal rekey' there with a key that opens it)\n", yellow("•"), s)
+	}
+
+	cmd.Println()
+	cmd.Println(bold("Recovery key — write these words down and keep them offline. They are shown only once:"))
+	out := cmd.OutOrStdout()
+	for i := 0; i < len(words); i += recoveryWordsPerLine {
+		var line []string
+		for j := i; j < min(i+recoveryWordsPerLine, len(words)); j++ {
+			line = append(line, fmt.Sprintf("%2d. %-9s", j+1, words[j]))
+		}
+		fmt.Fprintln(out, strings.TrimRight(strings.Join(line, " "), " "))
+	}
+	cmd.Println()
+
+	cmd.Println("Commit the changes to Git:")
+	cmd.Println(cyan("  git add " + strings.Join(gitFiles, " ")))
+	cmd.Println(cyan(`  git commit -m "Add recovery key"`))
+	if oldKey != "" {
+		cmd.Println(yellow("The previous recovery key still opens older copies in Git history; run 'envseal rekey --rotate' if its words may be exposed."))
+	}
+
+	return nil
+}
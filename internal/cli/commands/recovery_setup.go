@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+func newRecoverySetupCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Split the vault key into shares for recovery custodians",
+		Long: `Splits the DEK of the vault into one Shamir share per custodian, each
+encrypted to that custodian's public key and stored in the _envseal block.
+Any --threshold custodians can later recover the vault together.
+
+A custodian is a user of the manifest (NAME) or anyone else (NAME=PUBLIC_KEY).
+Running setup again replaces the previous shares; 'rekey --rotate' removes
+them, since they belong to the old key.`,
+		Example: `  envseal recovery setup --threshold 2 --custodian alice,bob,carol
+  envseal recovery setup --shares 5 --threshold 3 \
+      --custodian alice --custodian bob --custodian carol \
+      --custodian cto=age1ql3z7hjy54pw3hyww5... --custodian safe=age1yt8...`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRecoverySetup(cmd, deps)
+		},
+	}
+
+	cmd.Flags().Int("shares", 0, "Number of shares (default: one per custodian; must match the custodians given)")
+	cmd.Flags().Int("threshold", 0, "Number of shares needed to recover the vault")
+	cmd.Flags().StringSlice("custodian", nil, "Custodian as NAME (manifest user) or NAME=PUBLIC_KEY, repeatable")
+	_ = cmd.MarkFlagRequired("threshold")
+	_ = cmd.MarkFlagRequired("custodian")
+	return cmd
+}
+
+func runRecoverySetup(cmd *cobra.Command, deps Deps) error {
+	shares, err := cmd.Flags().GetInt("shares")
+	if err != nil {
+		return err
+	}
+	threshold, err := cmd.Flags().GetInt("threshold")
+	if err != nil {
+		return err
+	}
+	specs, err := cmd.Flags().GetStringSlice("custodian")
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	custodians, err := resolveCustodians(manifest, specs)
+	if err != nil {
+		return err
+	}
+	if shares != 0 && shares != len(custodians) {
+		return fmt.Errorf("--shares is %d but %d custodian(s) were given; each share needs a custodian", shares, len(custodians))
+	}
+	if threshold < 2 || threshold > len(custodians) {
+		return fmt.Errorf("--threshold must be between 2 and the number of custodians (%d)", len(custodians))
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+	if _, err := sf.Unlock(identities...); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
+	}
+	defer sf.Lock()
+
+	if previous := sf.Recovery(); previous != nil {
+		cmd.Println(yellow(fmt.Sprintf("⚠️  Replacing the previous recovery setup (%d of %d shares).",
+			previous.Threshold, len(previous.Shares))))
+	}
+
+	if err := sf.SetupRecovery(threshold, custodians); err != nil {
+		return fmt.Errorf("failed to set up recovery: %w", err)
+	}
+	if err := sf.Save(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", secretFilePath, err)
+	}
+
+	cmd.Printf("🔑 Recovery for %s: any %s of %d custodians\n", cyan(secretFilePath), bold(threshold), len(custodians))
+	if len(sf.Groups()) > 1 {
+		cmd.Println(yellow("⚠️  Only the default group is covered; secrets of other groups cannot be recovered with these shares."))
+	}
+	for _, c := range custodians {
+		cmd.Printf("  %s %s\n", green("✓"), c.Name)
+	}
+
+	cmd.Println()
+	cmd.Println("Commit the changes to Git:")
+	cmd.Println(cyan("  git add " + secretFilePath))
+	cmd.Println(cyan(`  git commit -m "Set up vault recovery"`))
+	cmd.Println("To recover, each custodian runs", bold("envseal recovery share"),
+		"and the shares are combined with", bold("envseal recovery unlock")+".")
+
+	return nil
+}
+
+// resolveCustodians turns NAME or NAME=PUBLIC_KEY specs into custodians,
+// looking names up in the manifest.
+func resolveCustodians(manifest *config.Manifest, specs []string) ([]config.Custodian, error) {
+	custodians := make([]config.Custodian, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+
+	for _, spec := range specs {
+		name, pubKey, explicit := strings.Cut(strings.TrimSpace(spec), "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid custodian %q", spec)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("custodian %q given twice", name)
+		}
+		seen[name] = true
+
+		if explicit {
+			normalized, err := crypto.NormalizeRecipient(pubKey)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key for custodian %q: %w", name, err)
+			}
+			pubKey = normalized
+		} else {
+			found := false
+			for _, u := range manifest.AccessControl {
+				if u.Name == name {
+					pubKey, found = u.PublicKey, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("custodian %q is not in %s (use NAME=PUBLIC_KEY)", name, config.ManifestFileName)
+			}
+		}
+
+		custodians = append(custodians, config.Custodian{Name: name, PublicKey: pubKey})
+	}
+
+	return custodians, nil
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+func newRecoveryShareCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "share",
+		Short: "Decrypt your recovery share to hand it over",
+		Long: `Decrypts the recovery share held by your identity and prints it to stdout,
+to be given (over a secure channel) to whoever runs 'envseal recovery unlock'.
+
+A share alone reveals nothing about the vault key, but anyone holding
+threshold shares can open the vault: only hand it over for a recovery.`,
+		Example: `  envseal recovery share > alice.share`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRecoveryShare(cmd, deps)
+		},
+	}
+	return cmd
+}
+
+func runRecoveryShare(cmd *cobra.Command, deps Deps) error {
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+
+	custodian, share, err := sf.RecoveryShare(identities...)
+	if err != nil {
+		if errors.Is(err, config.ErrNoRecovery) {
+			return fmt.Errorf("%w (run 'envseal recovery setup')", err)
+		}
+		return err
+	}
+
+	cmd.Println(color.YellowString("⚠️  Recovery share of %s for %s. Keep it secret; hand it over only for a recovery.",
+		custodian, secretFilePath))
+	fmt.Fprintln(cmd.OutOrStdout(), share)
+	return nil
+}
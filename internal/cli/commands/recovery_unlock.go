@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+func newRecoveryUnlockCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock [SHARE_FILE...]",
+		Short: "Combine recovery shares and grant a key access to the vault",
+		Long: `Rebuilds the vault key from the shares produced by 'envseal recovery share'
+and adds a recipient to the vault, so that it can be opened again after
+every recipient lost their key. No identity with access is needed.
+
+Shares are read from the given files, or from stdin ("-" or no argument),
+one ENVSEAL-SHARE-... line each; other lines are ignored. The recipient
+defaults to your own public key.`,
+		Example: `  envseal recovery unlock alice.share bob.share carol.share
+  cat *.share | envseal recovery unlock --recipient age1ql3z7hjy54pw3hyww5...`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRecoveryUnlock(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().String("recipient", "", "Public key to grant access to (default: your identity)")
+	return cmd
+}
+
+func runRecoveryUnlock(cmd *cobra.Command, args []string, deps Deps) error {
+	recipient, err := cmd.Flags().GetString("recipient")
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	if recipient == "" {
+		identities, err := deps.IdentityManager.Load(identityFilePath)
+		if err != nil {
+			return fmt.Errorf("identity error (use --recipient or run 'envseal-cli init'): %w", err)
+		}
+		if recipient, err = crypto.IdentityRecipient(identities[0]); err != nil {
+			return err
+		}
+	} else if recipient, err = crypto.NormalizeRecipient(recipient); err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	var shares []string
+	for _, path := range args {
+		data, err := readImportSource(cmd, path)
+		if err != nil {
+			return err
+		}
+		shares = append(shares, parseRecoveryShares(data)...)
+	}
+	if len(shares) == 0 {
+		return fmt.Errorf("no %s... line found in the input", config.RecoverySharePrefix)
+	}
+
+	sf, err := deps.SecretsStore.Load(secretFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
+	}
+	if err := sf.UnlockWithShares(shares); err != nil {
+		return err
+	}
+	defer sf.Lock()
+	cmd.Println(green(fmt.Sprintf("✓ Vault key rebuilt from %d share(s)", len(shares))))
+
+	recipients, err := sf.GroupRecipients(config.DefaultGroup)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(recipients, recipient) {
+		recipients = append(recipients, recipient)
+	}
+	if err := sf.RotateRecipients(recipients); err != nil {
+		return fmt.Errorf("failed to add recipient: %w", err)
+	}
+	if err := sf.Save(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", secretFilePath, err)
+	}
+	cmd.Println(green("✓ Access granted to ..." + shortKey(recipient)))
+	if len(sf.Groups()) > 1 {
+		cmd.Println(yellow("Recovery covers the default group only; secrets of other groups need one of their members."))
+	}
+
+	cmd.Printf("\n%s %s recovered.\n", bold("SUCCESS:"), secretFilePath)
+	if manifest, err := deps.ManifestStore.Load(); err == nil {
+		if _, ok := manifest.FindUserByPublicKey(recipient); !ok {
+			cmd.Println(yellow("The key is not in " + config.ManifestFileName + "; add it with 'envseal users add' or the next rekey removes it."))
+		}
+	}
+	cmd.Println("The shares have been revealed. Rotate the vault key and set up recovery again:")
+	cmd.Println(cyan("  envseal rekey --rotate"))
+	cmd.Println(cyan("  envseal recovery setup --threshold N --custodian ..."))
+
+	return nil
+}
+
+// parseRecoveryShares returns the share lines found in data.
+func parseRecoveryShares(data []byte) []string {
+	var shares []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, config.RecoverySharePrefix) && !slices.Contains(shares, line) {
+			shares = append(shares, line)
+		}
+	}
+	return shares
+}
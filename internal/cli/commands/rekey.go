@@ -2,9 +2,14 @@ package commands
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
 )
 
 func NewRekeyCommand(deps Deps) *cobra.Command {
@@ -14,8 +19,23 @@ func NewRekeyCommand(deps Deps) *cobra.Command {
 		Long: `Synchronizes the encrypted file with the users defined in envseal.yaml.
 
 Modes:
-  1) Standard (default): updates recipients header only.
-  2) Rotate (--rotate): generates a new DEK and re-encrypts all secrets (required for revocation).`,
+  1) Standard (default): updates recipients header and upgrades values
+     still stored in the legacy v1 encoding.
+  2) Rotate (--rotate): generates a new DEK and re-encrypts all secrets (required for revocation).
+     Refuses to run if any value is corrupt or unencrypted, unless --no-strict is given,
+     in which case corrupt values are dropped and unencrypted ones are encrypted.
+
+Users past their expires_at are left out of the recipients; run --rotate
+to make sure they cannot use a DEK they already had.
+
+Values are bound to the file name of their vault, so a vault renamed with
+git mv or copied to start another environment no longer decrypts. Run rekey
+--renamed-from with the old file name to re-encrypt its values for the new
+one:
+
+  envseal rekey -f secrets.prod.enc.yaml --renamed-from secrets.enc.yaml
+
+Once envseal.yaml declares an admin, only admins can rekey.`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runRekey(cmd, deps)
@@ -23,6 +43,8 @@ Modes:
 	}
 
 	cmd.Flags().Bool("rotate", false, "Generate a new master key and re-encrypt all data (revocation)")
+	cmd.Flags().String("renamed-from", "", "Previous file name of the vault, whose values are re-encrypted for its current name")
+	addNoStrictFlag(cmd)
 	return cmd
 }
 
@@ -31,6 +53,10 @@ func runRekey(cmd *cobra.Command, deps Deps) error {
 	if err != nil {
 		return err
 	}
+	renamedFrom, err := cmd.Flags().GetString("renamed-from")
+	if err != nil {
+		return err
+	}
 
 	green := color.New(color.FgGreen).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -39,7 +65,7 @@ func runRekey(cmd *cobra.Command, deps Deps) error {
 
 	cmd.Println("🔐 Starting rekey process...")
 
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
@@ -48,50 +74,146 @@ func runRekey(cmd *cobra.Command, deps Deps) error {
 	if err != nil {
 		return fmt.Errorf("failed to load manifest: %w", err)
 	}
+	if err := requireAdmin(deps, manifest); err != nil {
+		return err
+	}
 
 	sf, err := deps.SecretsStore.Load(secretFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
 	}
 
-	if err := sf.Unlock(identity); err != nil {
+	if _, err := sf.Unlock(identities...); err != nil {
 		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
 	}
 	defer sf.Lock()
 
-	recipients := manifest.GetPublicKeys()
+	if renamedFrom != "" {
+		rebound, err := sf.Rebind(renamedFrom)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt the values of %s: %w", filepath.Base(renamedFrom), err)
+		}
+		cmd.Println(green(fmt.Sprintf("✓ Re-encrypted %d value(s) written for %s under %s.",
+			len(rebound), filepath.Base(renamedFrom), filepath.Base(secretFilePath))))
+	}
+
+	recipients, err := manifest.VaultPublicKeys(secretFilePath, config.DefaultGroup)
+	if err != nil {
+		return err
+	}
 	if len(recipients) == 0 {
 		return fmt.Errorf("manifest has no recipients; add at least one user before rekey")
 	}
+	if env, ok := manifest.EnvironmentForFile(secretFilePath); ok {
+		cmd.Printf("Environment: %s\n", env.Name)
+	}
 	cmd.Printf("Target recipients: %d\n", len(recipients))
 
+	fileKeys, err := sf.GetRecipients()
+	if err != nil {
+		return fmt.Errorf("failed to read recipients: %w", err)
+	}
+	expired := expiredWithAccess(manifest, fileKeys)
+	if len(expired) > 0 {
+		cmd.Println(yellow("⚠️  Access expired for: " + strings.Join(expired, ", ") + " (not wrapping the DEK for them)"))
+	}
+
 	if rotate {
 		cmd.Println(yellow("⚠️  Rotation mode: re-encrypting all secrets..."))
 
-		all, err := sf.GetAllSecrets()
+		// Every group gets a new DEK, so all of them must be readable.
+		if locked := sf.LockedKeys(); len(locked) > 0 {
+			return fmt.Errorf("cannot rotate: you cannot open the group of %s", strings.Join(locked, ", "))
+		}
+		groups := sf.SecretGroups()
+		for k, g := range groups {
+			if g != config.DefaultGroup && !slices.Contains(manifest.GroupNames(), g) {
+				return fmt.Errorf("cannot rotate: %s is in group %q, which is not in %s", k, g, config.ManifestFileName)
+			}
+		}
+
+		all, err := readAllSecrets(cmd, sf)
 		if err != nil {
-			return fmt.Errorf("failed to read current secrets: %w", err)
+			return err
+		}
+		// With --no-strict, corrupt values are left out of all. They are
+		// bound to the old DEKs, so they are dropped rather than kept
+		// undecryptable for good.
+		var dropped []string
+		for _, k := range sf.SecretKeys() {
+			if _, ok := all[k]; !ok {
+				dropped = append(dropped, k)
+			}
 		}
 
-		// Rotation: generate new DEK + recipients, then re-encrypt every secret.
+		// Encrypted metadata is bound to the old DEKs too.
+		metas := make(map[string]config.SecretMeta)
+		for k := range all {
+			m, ok, err := sf.SecretMeta(k)
+			if err != nil {
+				return fmt.Errorf("failed to read metadata of %s: %w", k, err)
+			}
+			if ok {
+				metas[k] = m
+			}
+		}
+
+		// The shares belong to the old DEK; Init drops them.
+		hadRecovery := sf.Recovery() != nil
+
+		// Rotation: generate new DEKs + recipients, then re-encrypt every secret.
 		if err := sf.Init(recipients); err != nil {
 			return fmt.Errorf("failed to initialize new key: %w", err)
 		}
+		if err := rekeyGroups(cmd, sf, manifest); err != nil {
+			return err
+		}
 		for k, v := range all {
-			if err := sf.SetSecret(k, v); err != nil {
+			if err := sf.SetSecretInGroup(k, v, groups[k]); err != nil {
 				return fmt.Errorf("failed to re-encrypt %s: %w", k, err)
 			}
 		}
+		for k, m := range metas {
+			if err := sf.SetSecretMeta(k, m, manifest.EncryptSecretMetadata); err != nil {
+				return fmt.Errorf("failed to re-encrypt metadata of %s: %w", k, err)
+			}
+		}
+		for _, k := range dropped {
+			if err := sf.UnsetSecret(k); err != nil {
+				return fmt.Errorf("failed to drop %s: %w", k, err)
+			}
+		}
 
 		cmd.Println(green("✓ Keys rotated and data re-encrypted."))
+		if len(dropped) > 0 {
+			cmd.Println(yellow("⚠️  Dropped corrupt values: " + strings.Join(dropped, ", ")))
+		}
+		if hadRecovery {
+			cmd.Println(yellow("⚠️  Recovery shares were removed; run 'envseal recovery setup' again."))
+		}
 	} else {
 		cmd.Println(cyan("ℹ️  Standard mode: updating recipients header only..."))
 
 		if err := sf.RotateRecipients(recipients); err != nil {
 			return fmt.Errorf("failed to update recipients: %w", err)
 		}
+		if err := rekeyGroups(cmd, sf, manifest); err != nil {
+			return err
+		}
 
 		cmd.Println(green("✓ Access headers updated."))
+		if len(expired) > 0 {
+			cmd.Println(yellow("⚠️  Expired users may have kept the current DEK; run 'envseal rekey --rotate' to revoke them for good."))
+		}
+
+		// Values written before the v2 encoding are not bound to their key names.
+		upgraded, err := sf.UpgradeEncoding()
+		if err != nil {
+			return fmt.Errorf("failed to upgrade value encoding: %w", err)
+		}
+		if len(upgraded) > 0 {
+			cmd.Println(green(fmt.Sprintf("✓ Upgraded %d value(s) to the v2 encoding.", len(upgraded))))
+		}
 	}
 
 	if err := sf.Save(); err != nil {
@@ -105,3 +227,37 @@ func runRekey(cmd *cobra.Command, deps Deps) error {
 
 	return nil
 }
+
+// rekeyGroups wraps the DEK of every group declared in the manifest for its
+// members allowed in the vault's environment, creating the keys of new
+// groups. Groups the caller cannot open are left to one of their members.
+func rekeyGroups(cmd *cobra.Command, sf *config.SecretFile, manifest *config.Manifest) error {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	declared := manifest.GroupNames()
+	for _, group := range declared {
+		keys, err := manifest.VaultPublicKeys(sf.Path(), group)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(keys) == 0:
+			cmd.Println(yellow(fmt.Sprintf("⚠️  Group %q has no members allowed in %s; skipped.", group, sf.Path())))
+			continue
+		case sf.HasGroup(group) && !sf.CanOpenGroup(group):
+			cmd.Println(yellow(fmt.Sprintf("⚠️  You are not a member of group %q; a member must run rekey for it.", group)))
+			continue
+		}
+		if err := sf.RotateGroupRecipients(group, keys); err != nil {
+			return fmt.Errorf("failed to update recipients of group %s: %w", group, err)
+		}
+		cmd.Printf("  • group %s: %d recipient(s)\n", group, len(keys))
+	}
+
+	for _, group := range sf.Groups() {
+		if group != config.DefaultGroup && !slices.Contains(declared, group) {
+			cmd.Println(yellow(fmt.Sprintf("⚠️  Group %q is not in %s; its recipients are unchanged.", group, config.ManifestFileName)))
+		}
+	}
+	return nil
+}
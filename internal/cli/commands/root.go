@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/flootic/envseal/internal/cli/audit"
@@ -14,6 +15,8 @@ import (
 var (
 	secretFilePath   string
 	identityFilePath string
+	recoveryWords    bool
+	environmentName  string
 )
 
 func Execute() error {
@@ -22,7 +25,16 @@ func Execute() error {
 		Short:   "Secure Git-native secrets management for teams.",
 		Long:    "EnvSeal is a CLI tool to manage encrypted secrets files in your git repositories. It allows teams to securely share secrets without relying on external services.",
 		Version: "v0.1.0",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if recoveryWords {
+				identityFilePath = identitySourceRecoveryWords
+			}
+			if environmentName != "" {
+				if err := selectEnvironment(cmd, environmentName); err != nil {
+					return err
+				}
+			}
+
 			cmdPath := strings.TrimPrefix(cmd.CommandPath(), "envseal ")
 			message := strings.Join(audit.SanitizeArgs(os.Args[1:]), " ")
 			if cmdPath == message {
@@ -30,6 +42,7 @@ func Execute() error {
 			}
 
 			_ = audit.Log(cmdPath, message)
+			return nil
 		},
 	}
 
@@ -47,12 +60,33 @@ func Execute() error {
 		return fmt.Errorf("failed to get default identity file path: %w", err)
 	}
 
+	defaultIdentitySource := defaultIdentityFilePath
+	if v := os.Getenv(config.IdentitySourceEnvVar); v != "" {
+		defaultIdentitySource = v
+	}
+
 	rootCmd.PersistentFlags().StringVarP(
 		&identityFilePath,
 		"identity",
 		"i",
-		defaultIdentityFilePath,
-		fmt.Sprintf("Path to the identity key file (defaults to %s).", defaultIdentityFilePath),
+		defaultIdentitySource,
+		fmt.Sprintf("Identity key file path, or env:VAR, fd:N, cmd:COMMAND, file:PATH (defaults to $%s or %s).",
+			config.IdentitySourceEnvVar, defaultIdentityFilePath),
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&environmentName,
+		"env",
+		"",
+		"Environment declared in envseal.yaml whose vault to use (instead of --file).",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&recoveryWords,
+		"recovery-words",
+		false,
+		fmt.Sprintf("Use the recovery key instead of --identity, entering its words (or reading $%s).",
+			config.RecoveryWordsEnvVar),
 	)
 
 	deps := DefaultDeps()
@@ -60,15 +94,45 @@ func Execute() error {
 	rootCmd.AddCommand(NewInitCommand(deps))
 	rootCmd.AddCommand(NewExecCommand(deps))
 	rootCmd.AddCommand(NewSetCommand(deps))
+	rootCmd.AddCommand(NewGetCommand(deps))
 	rootCmd.AddCommand(NewUnsetCommand(deps))
+	rootCmd.AddCommand(NewEditCommand(deps))
+	rootCmd.AddCommand(NewImportCommand(deps))
+	rootCmd.AddCommand(NewExportCommand(deps))
+	rootCmd.AddCommand(NewLsCommand(deps))
 	rootCmd.AddCommand(NewUsersCommand(deps))
+	rootCmd.AddCommand(NewGroupsCommand(deps))
 	rootCmd.AddCommand(NewRekeyCommand(deps))
+	rootCmd.AddCommand(NewRecoveryCommand(deps))
+	rootCmd.AddCommand(NewRecoveryKeyCommand(deps))
 	rootCmd.AddCommand(NewJoinCommand(deps))
 	rootCmd.AddCommand(NewDoctorCommand(deps))
 	rootCmd.AddCommand(NewPrintCommand(deps))
 	rootCmd.AddCommand(NewWhoamiCommand(deps))
+	rootCmd.AddCommand(NewIdentityCommand(deps))
 	rootCmd.AddCommand(NewStatusCommand(deps))
+	rootCmd.AddCommand(NewMigrateCommand(deps))
+	rootCmd.AddCommand(NewVerifyCommand(deps))
 	rootCmd.AddCommand(NewAuditLogCommand())
 	rootCmd.AddCommand(NewHookCommand())
+	rootCmd.AddCommand(NewGitDiffCommand(deps))
+	rootCmd.AddCommand(NewGitMergeCommand(deps))
 	return rootCmd.Execute()
 }
+
+// selectEnvironment points secretFilePath at the vault of environment name.
+func selectEnvironment(cmd *cobra.Command, name string) error {
+	manifest, err := config.LoadManifest()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	env, ok := manifest.FindEnvironment(name)
+	if !ok {
+		return fmt.Errorf("environment %q is not declared in %s", name, config.ManifestFileName)
+	}
+	if cmd.Flags().Changed("file") && filepath.Clean(secretFilePath) != filepath.Clean(env.File) {
+		return fmt.Errorf("--file %s conflicts with --env %s (%s)", secretFilePath, name, env.File)
+	}
+	secretFilePath = env.File
+	return nil
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+const noStrictFlag = "no-strict"
+
+// projectVaultPaths lists the vaults of the current project: the active
+// vault, the vault of every environment of manifest (which may be nil),
+// every *.enc.yaml file in the current directory and every one Git tracks
+// below it.
+func projectVaultPaths(manifest *config.Manifest) []string {
+	paths, _ := filepath.Glob("*.enc.yaml")
+	paths = append(paths, secretFilePath)
+	if manifest != nil {
+		paths = append(paths, manifest.EnvironmentFiles()...)
+	}
+	if tracked, err := gitTrackedVaults(); err == nil {
+		paths = append(paths, tracked...)
+	}
+
+	seen := make(map[string]struct{}, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		p = filepath.Clean(p)
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return out
+}
+
+func addNoStrictFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(noStrictFlag, false,
+		"Do not fail on corrupt or unencrypted values: skip corrupt ones and use plaintext ones as-is")
+}
+
+// readAllSecrets decrypts every value in sf that the caller can open. In
+// strict mode (the default) a corrupt or unencrypted value aborts with the
+// full list of offending keys; with --no-strict they are reported as warnings
+// and only usable values are returned.
+func readAllSecrets(cmd *cobra.Command, sf *config.SecretFile) (map[string]string, error) {
+	noStrict, err := cmd.Flags().GetBool(noStrictFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked := sf.LockedKeys(); len(locked) > 0 {
+		cmd.PrintErrln(color.CyanString("ℹ️  %d secret(s) belong to groups you are not a member of and are skipped.", len(locked)))
+	}
+
+	if !noStrict {
+		all, err := sf.GetAllSecrets()
+		var invalid *config.InvalidSecretsError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("%s contains %w; run 'envseal verify' for details or pass --%s",
+				secretFilePath, err, noStrictFlag)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secrets: %w", err)
+		}
+		return all, nil
+	}
+
+	all, invalid, err := sf.GetAllSecretsLenient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets: %w", err)
+	}
+	if invalid != nil {
+		yellow := color.New(color.FgYellow).SprintFunc()
+		if len(invalid.Corrupt) > 0 {
+			cmd.PrintErrln(yellow("⚠️  Skipping corrupt values: " + strings.Join(invalid.Corrupt, ", ")))
+		}
+		if len(invalid.Unencrypted) > 0 {
+			cmd.PrintErrln(yellow("⚠️  Using unencrypted values as-is: " + strings.Join(invalid.Unencrypted, ", ")))
+		}
+	}
+	return all, nil
+}
+
+// authorName names the caller in secret metadata: their user name in the
+// manifest, or the end of their public key if they are not listed.
+func authorName(manifest *config.Manifest, identities []age.Identity) string {
+	var fallback string
+	for _, id := range identities {
+		pk, err := crypto.IdentityRecipient(id)
+		if err != nil {
+			continue
+		}
+		if u, ok := manifest.FindUserByPublicKey(pk); ok {
+			return u.Name
+		}
+		if fallback == "" {
+			fallback = "..." + shortKey(pk)
+		}
+	}
+	return fallback
+}
+
+// touchSecretMeta records in meta that author changed the secret's value at
+// now, if the manifest tracks changes or the secret has a rotation policy
+// (which counts from the last change).
+func touchSecretMeta(manifest *config.Manifest, meta *config.SecretMeta, author string, now time.Time) {
+	if !manifest.TrackSecretChanges && meta.RotateEvery == "" {
+		return
+	}
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt, meta.CreatedBy = now, author
+	}
+	meta.UpdatedAt, meta.UpdatedBy = now, author
+}
@@ -2,35 +2,98 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
 func NewSetCommand(deps Deps) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "set KEY=VALUE [KEY=VALUE...]",
+		Use:   "set KEY=VALUE|KEY [KEY=VALUE|KEY...]",
 		Short: "Add or update encrypted secrets",
-		Long:  "Encrypts provided values and writes them to secrets.enc.yaml.",
+		Long: `Encrypts provided values and writes them to secrets.enc.yaml.
+
+Values given as KEY=VALUE end up in shell history and process listings. A
+bare KEY instead takes its value from:
+  (default)       a prompt on the terminal, with echo disabled
+  --stdin         standard input, byte for byte (no newline is stripped)
+  --from-file F   the content of file F, e.g. a certificate or binary key
+  --generate[=S]  a random value: alnum:N (default alnum:32), hex:N or
+                  base64:N (N random bytes), or uuid
+
+With --group the secrets are encrypted with the key of that group (declared
+in envseal.yaml), so only its members can read them. Otherwise a secret
+stays in its current group, and new secrets go to the default group.
+
+Secrets can have metadata under secrets_meta: --description, --owner,
+--rotate-every and --expires set it; with only those flags (and no value
+source), a bare KEY edits the metadata of an existing secret without
+changing its value. When it was created and last updated, and by whom, is
+recorded for secrets with a rotation policy, or for every secret if
+envseal.yaml sets track_secret_changes: true. Metadata is plain YAML unless
+envseal.yaml sets encrypt_secret_metadata: true.`,
 		Example: `  envseal set DATABASE_URL=postgres://localhost:5432/db
-  envseal set API_KEY=12345 DEBUG=true`,
+  envseal set API_KEY=12345 DEBUG=true
+  envseal set --group ci NPM_TOKEN=npm_abc123
+  envseal set DB_PASSWORD
+  vault read -field=key secret/tls | envseal set TLS_KEY --stdin
+  envseal set TLS_CERT --from-file cert.pem
+  envseal set SESSION_SECRET --generate=hex:32
+  envseal set STRIPE_KEY=sk_live_... --owner payments --rotate-every 90d
+  envseal set LEGACY_API_KEY --description "Old billing API, remove after migration"`,
 		Args: validateSetArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runSet(cmd, args, deps)
 		},
 	}
+
+	cmd.Flags().String("group", "", "Group whose members can read the secrets (see groups in envseal.yaml)")
+	cmd.Flags().Bool("stdin", false, "Read the value of KEY from standard input")
+	cmd.Flags().String("from-file", "", "Read the value of KEY from this file")
+	cmd.Flags().String("generate", "", "Set each KEY to a random value (alnum:N, hex:N, base64:N or uuid)")
+	cmd.Flags().Lookup("generate").NoOptDefVal = crypto.DefaultGenerateSpec
+	cmd.Flags().String("description", "", "What the secret is for")
+	cmd.Flags().String("owner", "", "Who is responsible for the secret (user, team or email)")
+	cmd.Flags().String("rotate-every", "", "Rotation policy, e.g. 90d, 12w or 720h (\"none\" removes it)")
+	cmd.Flags().String("expires", "", "Date (YYYY-MM-DD) or RFC 3339 time after which the secret is invalid (\"none\" removes it)")
 	return cmd
 }
 
+// setMetaFlags are the flags of 'set' that edit secret metadata.
+var setMetaFlags = []string{"description", "owner", "rotate-every", "expires"}
+
+// setSourceFlags are the flags of 'set' that give the value of a bare KEY.
+var setSourceFlags = []string{"stdin", "from-file", "generate"}
+
 func validateSetArgs(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return cobra.MinimumNArgs(1)(cmd, args)
 	}
 
+	var sources []string
+	for _, name := range setSourceFlags {
+		if cmd.Flags().Changed(name) {
+			sources = append(sources, "--"+name)
+		}
+	}
+	if len(sources) > 1 {
+		return fmt.Errorf("%s cannot be combined", strings.Join(sources, " and "))
+	}
+	if len(sources) == 1 && sources[0] != "--generate" && len(args) != 1 {
+		return fmt.Errorf("%s sets a single KEY", sources[0])
+	}
+
 	for _, a := range args {
 		key, _, ok := strings.Cut(a, "=")
-		if !ok {
-			return fmt.Errorf("invalid argument %q: expected KEY=VALUE", a)
+		if ok && len(sources) > 0 {
+			return fmt.Errorf("invalid argument %q: give a bare KEY with %s", a, sources[0])
 		}
 		key = strings.TrimSpace(key)
 		if key == "" {
@@ -40,38 +103,133 @@ func validateSetArgs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// readSetValue returns the value of a bare KEY from the source chosen with
+// the flags of 'set', or false if only its metadata is being edited.
+func readSetValue(cmd *cobra.Command, key string) (string, bool, error) {
+	switch {
+	case cmd.Flags().Changed("stdin"):
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return string(data), true, nil
+
+	case cmd.Flags().Changed("from-file"):
+		path, _ := cmd.Flags().GetString("from-file")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return string(data), true, nil
+
+	case cmd.Flags().Changed("generate"):
+		spec, _ := cmd.Flags().GetString("generate")
+		value, err := crypto.GenerateSecret(spec)
+		if err != nil {
+			return "", false, fmt.Errorf("--generate: %w", err)
+		}
+		return value, true, nil
+
+	case slices.ContainsFunc(setMetaFlags, cmd.Flags().Changed):
+		return "", false, nil
+	}
+
+	value, err := readPassphrase(fmt.Sprintf("Value for %s: ", key))
+	if err != nil {
+		return "", false, fmt.Errorf("cannot prompt for %s: %w (use --stdin or --from-file)", key, err)
+	}
+	defer clear(value)
+	if len(value) == 0 {
+		return "", false, fmt.Errorf("empty value for %s (use %s= to store an empty string)", key, key)
+	}
+	return string(value), true, nil
+}
+
 func runSet(cmd *cobra.Command, args []string, deps Deps) error {
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	group, err := cmd.Flags().GetString("group")
+	if err != nil {
+		return err
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
 
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
 	sf, err := deps.SecretsStore.Load(secretFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
 	}
 
-	if err := sf.Unlock(identity); err != nil {
+	if _, err := sf.Unlock(identities...); err != nil {
 		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
 	}
 	defer sf.Lock()
 
-	type pair struct{ k, v string }
+	if group != "" && !sf.HasGroup(group) {
+		if err := createVaultGroup(cmd, manifest, sf, group); err != nil {
+			return err
+		}
+	}
+
+	type pair struct {
+		k, v     string
+		hasValue bool
+	}
 	pairs := make([]pair, 0, len(args))
 	for _, a := range args {
-		k, v, _ := strings.Cut(a, "=")
+		k, v, ok := strings.Cut(a, "=")
+		k = strings.TrimSpace(k)
+		if !ok {
+			if v, ok, err = readSetValue(cmd, k); err != nil {
+				return err
+			}
+		}
 		pairs = append(pairs, pair{
-			k: strings.TrimSpace(k),
+			k: k,
 			// don't trim value: spaces may be intentional
-			v: v,
+			v:        v,
+			hasValue: ok,
 		})
 	}
 
+	author := authorName(manifest, identities)
+	now := time.Now().UTC().Truncate(time.Second)
+
 	for _, p := range pairs {
-		if err := sf.SetSecret(p.k, p.v); err != nil {
-			return fmt.Errorf("failed to set %s: %w", p.k, err)
+		if p.hasValue {
+			if err := sf.SetSecretInGroup(p.k, p.v, group); err != nil {
+				return fmt.Errorf("failed to set %s: %w", p.k, err)
+			}
+		}
+
+		meta, _, err := sf.SecretMeta(p.k)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of %s: %w", p.k, err)
+		}
+		before := meta
+		if err := applySetMetaFlags(cmd, &meta); err != nil {
+			return err
+		}
+		if p.hasValue {
+			touchSecretMeta(manifest, &meta, author, now)
+		}
+		if !meta.Equal(before) {
+			if err := sf.SetSecretMeta(p.k, meta, manifest.EncryptSecretMetadata); err != nil {
+				return fmt.Errorf("failed to set metadata of %s: %w", p.k, err)
+			}
+		}
+
+		if p.hasValue {
+			cmd.Printf("✓ Set %s\n", p.k)
+		} else {
+			cmd.Printf("✓ Updated metadata of %s\n", p.k)
 		}
-		cmd.Printf("✓ Set %s\n", p.k)
 	}
 
 	if err := sf.Save(); err != nil {
@@ -81,3 +239,48 @@ func runSet(cmd *cobra.Command, args []string, deps Deps) error {
 	cmd.Printf("Updated %s\n", secretFilePath)
 	return nil
 }
+
+// applySetMetaFlags copies the metadata flags given to 'set' into meta.
+func applySetMetaFlags(cmd *cobra.Command, meta *config.SecretMeta) error {
+	fields := map[string]*string{
+		"description":  &meta.Description,
+		"owner":        &meta.Owner,
+		"rotate-every": &meta.RotateEvery,
+		"expires":      &meta.ExpiresAt,
+	}
+	for _, name := range setMetaFlags {
+		if !cmd.Flags().Changed(name) {
+			continue
+		}
+		v, err := cmd.Flags().GetString(name)
+		if err != nil {
+			return err
+		}
+		v = strings.TrimSpace(v)
+		if (name == "rotate-every" || name == "expires") && v == "none" {
+			v = ""
+		}
+		*fields[name] = v
+	}
+	if err := meta.Validate(); err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	return nil
+}
+
+// createVaultGroup gives the vault a DEK for a group declared in the
+// manifest, wrapped for the group's members.
+func createVaultGroup(cmd *cobra.Command, manifest *config.Manifest, sf *config.SecretFile, group string) error {
+	keys, err := manifest.VaultPublicKeys(sf.Path(), group)
+	if err != nil {
+		return fmt.Errorf("%w (declare it under groups in %s)", err, config.ManifestFileName)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("group %q has no members", group)
+	}
+	if err := sf.RotateGroupRecipients(group, keys); err != nil {
+		return fmt.Errorf("failed to create key of group %s: %w", group, err)
+	}
+	cmd.Printf("✓ Created key of group %s (%d recipient(s))\n", group, len(keys))
+	return nil
+}
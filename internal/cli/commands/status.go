@@ -2,10 +2,16 @@ package commands
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"filippo.io/age"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
 func NewStatusCommand(deps Deps) *cobra.Command {
@@ -34,12 +40,23 @@ func runStatus(cmd *cobra.Command, deps Deps) error {
 	cmd.Printf("%-20s %s\n", "Active Vault:", cyan(secretFilePath))
 
 	// Load Local Identity
-	identity, errIdentity := deps.IdentityManager.Load(identityFilePath)
+	identities, errIdentity := deps.IdentityManager.Load(identityFilePath)
+	myPubKey := ""
+	if errIdentity == nil {
+		myPubKey, errIdentity = crypto.IdentityRecipient(identities[0])
+	}
+	myPubKeys := make(map[string]bool, len(identities))
+	for _, id := range identities {
+		if pk, err := crypto.IdentityRecipient(id); err == nil {
+			myPubKeys[pk] = true
+		}
+	}
 	if errIdentity != nil {
 		cmd.Printf("%-20s %s\n", "Local Identity:", red("Missing (run 'envseal-cli init')"))
+	} else if len(identities) > 1 {
+		cmd.Printf("%-20s %s...%s (+%d more)\n", "Local Identity:", green("OK "), shortKey(myPubKey), len(identities)-1)
 	} else {
-		pubKey := identity.Recipient().String()
-		cmd.Printf("%-20s %s...%s\n", "Local Identity:", green("OK "), pubKey[len(pubKey)-8:])
+		cmd.Printf("%-20s %s...%s\n", "Local Identity:", green("OK "), shortKey(myPubKey))
 	}
 
 	// Load Manifest & File State
@@ -55,54 +72,166 @@ func runStatus(cmd *cobra.Command, deps Deps) error {
 		return nil
 	}
 
+	if env, ok := manifest.EnvironmentForFile(secretFilePath); ok {
+		cmd.Printf("%-20s %s\n", "Environment:", cyan(env.Name))
+	}
+
+	if v := sf.Version(); v < config.FormatVersion {
+		cmd.Printf("%-20s %s\n", "Format Version:", yellow(fmt.Sprintf("v%d (run 'envseal migrate')", v)))
+	} else {
+		cmd.Printf("%-20s v%d\n", "Format Version:", v)
+	}
+
 	// Access Check
 	canDecrypt := false
-	if identity != nil {
-		if err := sf.Unlock(identity); err == nil {
+	var matched age.Identity
+	if len(identities) > 0 {
+		if id, err := sf.Unlock(identities...); err == nil {
 			canDecrypt = true
+			matched = id
 			defer sf.Lock()
 		}
 	}
 
-	if canDecrypt {
+	if canDecrypt && len(identities) > 1 {
+		pk, _ := crypto.IdentityRecipient(matched)
+		cmd.Printf("%-20s %s (with ...%s)\n", "Vault Access:", green("UNLOCKED"), shortKey(pk))
+	} else if canDecrypt {
 		cmd.Printf("%-20s %s\n", "Vault Access:", green("UNLOCKED"))
 	} else {
 		cmd.Printf("%-20s %s\n", "Vault Access:", red("LOCKED (Access Denied)"))
 	}
 
+	if groups := sf.Groups(); len(groups) > 1 {
+		labels := make([]string, 0, len(groups))
+		for _, g := range groups {
+			if sf.CanOpenGroup(g) {
+				labels = append(labels, green(g))
+			} else {
+				labels = append(labels, red(g+" (no access)"))
+			}
+		}
+		cmd.Printf("%-20s %s\n", "Groups:", strings.Join(labels, ", "))
+	}
+
 	cmd.Println(strings.Repeat("-", 40))
 	cmd.Printf("👥 %s\n", bold("Access Control List"))
 
 	// Detect Drift
-	manifestKeys := manifest.GetPublicKeys()
 	fileKeys, err := sf.GetRecipients()
 	if err != nil {
 		fileKeys = []string{}
 	}
 
+	allowed := vaultAllowedKeys(manifest, sf.Path())
+
+	now := time.Now()
+	classicalUsers := 0
+	printUser := func(user config.User) {
+		hasRealAccess := slices.Contains(fileKeys, user.PublicKey)
+
+		var statusTag string
+		switch {
+		case !allowed[user.PublicKey] && hasRealAccess:
+			statusTag = yellow("[PENDING REVOKE]")
+		case !allowed[user.PublicKey]:
+			statusTag = "[NO ACCESS]"
+		case !hasRealAccess:
+			statusTag = yellow("[PENDING REKEY]")
+		default:
+			statusTag = green("[SYNCED]")
+		}
+
+		isMe := ""
+		if myPubKeys[user.PublicKey] {
+			isMe = cyan(" (You)")
+		}
+
+		keyTag := ""
+		if !crypto.IsPostQuantumRecipient(user.PublicKey) {
+			keyTag = yellow(" [CLASSICAL]")
+			classicalUsers++
+		}
+
+		expiryTag := ""
+		switch {
+		case user.Expired(now):
+			expiryTag = red(" [EXPIRED " + user.ExpiresAt + "]")
+		case user.ExpiresSoon(now):
+			expiryTag = yellow(" [EXPIRES " + user.ExpiresAt + "]")
+		}
+
+		groupTag := ""
+		if groups := manifest.UserGroups(user.Name); len(groups) > 0 {
+			groupTag = " (" + strings.Join(groups, ", ") + ")"
+		}
+
+		cmd.Printf("    • %-15s %s%s%s%s%s\n", user.Name, statusTag, expiryTag, keyTag, groupTag, isMe)
+	}
+
+	var admins, members []config.User
 	for _, user := range manifest.AccessControl {
-		hasRealAccess := false
-		for _, fk := range fileKeys {
-			if fk == user.PublicKey {
-				hasRealAccess = true
-				break
-			}
+		if user.IsAdmin() {
+			admins = append(admins, user)
+		} else {
+			members = append(members, user)
 		}
+	}
+	if len(admins) == 0 {
+		cmd.Printf("  Admins: %s\n", yellow("none declared (anyone can manage users and rekey)"))
+	} else {
+		cmd.Println("  Admins:")
+		for _, user := range admins {
+			printUser(user)
+		}
+	}
+	if len(members) > 0 {
+		cmd.Println("  Members:")
+		for _, user := range members {
+			printUser(user)
+		}
+	}
 
+	if manifest.RecoveryKey != "" {
 		statusTag := green("[SYNCED]")
-		if !hasRealAccess {
+		if !slices.Contains(fileKeys, manifest.RecoveryKey) {
 			statusTag = yellow("[PENDING REKEY]")
 		}
+		cmd.Printf("  • %-17s %s\n", "(recovery key)", statusTag)
+	}
 
-		isMe := ""
-		if identity != nil && user.PublicKey == identity.Recipient().String() {
-			isMe = cyan(" (You)")
+	if classicalUsers > 0 {
+		cmd.Printf("\n%s\n", yellow(fmt.Sprintf("⚠️  %d user(s) on classical-only keys: the DEK wrapped for them is exposed to harvest-now-decrypt-later attacks.", classicalUsers)))
+		cmd.Printf("    Hybrid keys are created with %s (use a new --identity path to keep the old key).\n", bold("envseal whoami --generate --pq"))
+	}
+
+	var overdue, expiredSecrets []string
+	for _, k := range sf.SecretKeys() {
+		meta, _, err := sf.SecretMeta(k)
+		if err != nil {
+			continue
+		}
+		if meta.RotationOverdue(now) {
+			overdue = append(overdue, k)
+		}
+		if meta.Expired(now) {
+			expiredSecrets = append(expiredSecrets, k)
 		}
+	}
+	if len(overdue) > 0 {
+		cmd.Printf("\n%s\n", yellow(fmt.Sprintf("⚠️  %d secret(s) overdue for rotation: %s", len(overdue), strings.Join(overdue, ", "))))
+		cmd.Printf("    Set new values with %s (see %s for details).\n", bold("envseal set KEY=VALUE"), bold("envseal ls"))
+	}
+	if len(expiredSecrets) > 0 {
+		cmd.Printf("\n%s\n", yellow(fmt.Sprintf("⚠️  %d secret(s) past their expiry: %s", len(expiredSecrets), strings.Join(expiredSecrets, ", "))))
+	}
 
-		cmd.Printf("  • %-15s %s%s\n", user.Name, statusTag, isMe)
+	if expired := expiredWithAccess(manifest, fileKeys); len(expired) > 0 {
+		cmd.Printf("\n%s\n", red(fmt.Sprintf("🚨 %d expired user(s) can still open this vault: %s", len(expired), strings.Join(expired, ", "))))
+		cmd.Printf("    Run %s to revoke their access.\n", bold("envseal rekey --rotate"))
 	}
 
-	if len(manifestKeys) != len(fileKeys) {
+	if !vaultInSync(manifest, sf) {
 		cmd.Printf("\n%s\n", yellow("⚠️  DRIFT DETECTED: The manifest and the encrypted file are out of sync."))
 		cmd.Printf("    Run %s to apply changes.\n", bold("envseal-cli rekey"))
 	} else {
@@ -111,3 +240,54 @@ func runStatus(cmd *cobra.Command, deps Deps) error {
 
 	return nil
 }
+
+// vaultAllowedKeys returns the public keys the manifest grants access to at
+// least one group of the vault at path.
+func vaultAllowedKeys(manifest *config.Manifest, path string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, group := range append([]string{config.DefaultGroup}, manifest.GroupNames()...) {
+		keys, _ := manifest.VaultPublicKeys(path, group)
+		for _, k := range keys {
+			allowed[k] = true
+		}
+	}
+	return allowed
+}
+
+// expiredWithAccess returns the names of the expired users whose public key
+// is among the vault recipients fileKeys.
+func expiredWithAccess(manifest *config.Manifest, fileKeys []string) []string {
+	var names []string
+	for _, u := range manifest.ExpiredUsers(time.Now()) {
+		if slices.Contains(fileKeys, u.PublicKey) {
+			names = append(names, u.Name)
+		}
+	}
+	return names
+}
+
+// vaultInSync reports whether the DEK of every group declared in the
+// manifest is wrapped for exactly the group's members allowed in the vault.
+func vaultInSync(manifest *config.Manifest, sf *config.SecretFile) bool {
+	for _, group := range append([]string{config.DefaultGroup}, manifest.GroupNames()...) {
+		want, err := manifest.VaultPublicKeys(sf.Path(), group)
+		if err != nil {
+			return false
+		}
+		have, _ := sf.GroupRecipients(group)
+		slices.Sort(want)
+		slices.Sort(have)
+		if !slices.Equal(slices.Compact(want), have) {
+			return false
+		}
+	}
+	return true
+}
+
+// shortKey returns the last characters of a public key, enough to tell keys apart.
+func shortKey(pubKey string) string {
+	if len(pubKey) <= 8 {
+		return pubKey
+	}
+	return pubKey[len(pubKey)-8:]
+}
@@ -35,7 +35,7 @@ func validateUnsetArgs(cmd *cobra.Command, args []string) error {
 }
 
 func runUnset(cmd *cobra.Command, args []string, deps Deps) error {
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	identities, err := deps.IdentityManager.Load(identityFilePath)
 	if err != nil {
 		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
 	}
@@ -45,7 +45,7 @@ func runUnset(cmd *cobra.Command, args []string, deps Deps) error {
 		return fmt.Errorf("failed to load %s: %w", secretFilePath, err)
 	}
 
-	if err := sf.Unlock(identity); err != nil {
+	if _, err := sf.Unlock(identities...); err != nil {
 		return fmt.Errorf("failed to unlock %s: %w", secretFilePath, err)
 	}
 	defer sf.Lock()
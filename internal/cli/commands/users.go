@@ -1,19 +1,62 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
+var errNotAdmin = errors.New("only admins can do this")
+
 // NewUsersCommand creates the parent command for user management.
 func NewUsersCommand(deps Deps) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "users",
 		Short: "Manage access control (manifest)",
-		Long:  `Add or remove users from the envseal.yaml manifest.`,
+		Long: `Add or remove users from the envseal.yaml manifest.
+
+Once a user has the admin role, only admins can add, remove or promote users,
+change group membership and rekey vaults.`,
 	}
 
 	// Register subcommands
 	cmd.AddCommand(newUsersAddCommand(deps))
 	cmd.AddCommand(newUsersRemoveCommand(deps))
+	cmd.AddCommand(newUsersImportCommand(deps))
+	cmd.AddCommand(newUsersRoleCommand(deps))
 	return cmd
 }
+
+// requireAdmin fails unless the local identity belongs to an admin of
+// manifest, or the manifest has no admin yet.
+func requireAdmin(deps Deps, manifest *config.Manifest) error {
+	admins := manifest.Admins()
+	if len(admins) == 0 {
+		return nil
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+	pubKeys := make([]string, 0, len(identities))
+	for _, id := range identities {
+		if pk, err := crypto.IdentityRecipient(id); err == nil {
+			pubKeys = append(pubKeys, pk)
+		}
+	}
+	if manifest.CanAdminister(pubKeys...) {
+		return nil
+	}
+
+	names := make([]string, 0, len(admins))
+	for _, u := range admins {
+		names = append(names, u.Name)
+	}
+	return fmt.Errorf("%w (admins: %s)", errNotAdmin, strings.Join(names, ", "))
+}
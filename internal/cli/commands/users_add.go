@@ -9,8 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"filippo.io/age"
 	"github.com/fatih/color"
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
 	"github.com/flootic/envseal/internal/cli/p2p"
 	"github.com/spf13/cobra"
 )
@@ -28,15 +29,24 @@ func newUsersAddCommand(deps Deps) *cobra.Command {
 		Short: "Add a user to the manifest",
 		Long: `Adds a user alias and public key to envseal.yaml.
 
-The second argument is an Age public key (age1...).
+The second argument is an Age public key (age1...) or an SSH public key
+(ssh-ed25519 / ssh-rsa, quoted; any trailing comment is dropped).
 With --p2p, it is instead a 6-digit code from 'envseal join' which triggers
 a local network scan via mDNS to discover the public key.
 
+--role admin makes the user an admin; --group adds them to groups declared
+(or created) under groups in envseal.yaml. --expires limits access to a date
+(inclusive) or an RFC 3339 time: once it passes, 'rekey' stops wrapping the
+DEK for the user.
+
 Note: Adding a user does NOT grant access to already-encrypted secrets.
 You must run 'envseal-cli rekey' afterwards to update recipients.`,
 		Example: `  envseal-cli users add jane age1ql3z7hjy54pw3hyww5...
+  envseal-cli users add alice "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI... alice@laptop"
   envseal-cli users add jane --p2p 482910
-  envseal-cli users add ci-server age1yt8...`,
+  envseal-cli users add ci-server age1yt8...
+  envseal-cli users add bob age1x9f... --role admin --group backend --group sre
+  envseal-cli users add contractor age1k2m... --expires 2026-12-31`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runUsersAdd(cmd, args, deps)
@@ -44,6 +54,9 @@ You must run 'envseal-cli rekey' afterwards to update recipients.`,
 	}
 
 	cmd.Flags().Bool("p2p", false, "Treat the second argument as a join code and scan the local network via mDNS")
+	cmd.Flags().String("role", config.RoleMember, "Role of the user: admin or member")
+	cmd.Flags().StringSlice("group", nil, "Add the user to this group (repeatable)")
+	cmd.Flags().String("expires", "", "End the user's access after this date (YYYY-MM-DD) or RFC 3339 time")
 
 	return cmd
 }
@@ -57,25 +70,57 @@ func runUsersAdd(cmd *cobra.Command, args []string, deps Deps) error {
 		return fmt.Errorf("invalid alias %q (allowed: letters, numbers, '_', '.', '-', 2-64 chars)", alias)
 	}
 
-	// 1. Resolve the public key and retrieve the optional ACK trigger function
-	pubKey, ackFunc, err := resolvePublicKey(cmd, args)
+	role, _ := cmd.Flags().GetString("role")
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !config.ValidRole(role) {
+		return config.ErrInvalidRole
+	}
+	groups, _ := cmd.Flags().GetStringSlice("group")
+	expires, _ := cmd.Flags().GetString("expires")
+	if expires != "" {
+		expiry, err := config.ParseExpiry(expires)
+		if err != nil {
+			return fmt.Errorf("--expires: %w", err)
+		}
+		if !expiry.After(time.Now()) {
+			return fmt.Errorf("--expires %s is in the past", expires)
+		}
+	}
+
+	manifest, err := deps.ManifestStore.Load()
 	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	if err := requireAdmin(deps, manifest); err != nil {
 		return err
 	}
 
-	// Validate Age recipient format.
-	if _, err := age.ParseX25519Recipient(pubKey); err != nil {
-		return fmt.Errorf("invalid public key format: %w", err)
+	// 1. Resolve the public key and retrieve the optional ACK trigger function
+	pubKey, ackFunc, err := resolvePublicKey(cmd, args)
+	if err != nil {
+		return err
 	}
 
-	manifest, err := deps.ManifestStore.Load()
+	// Validate the recipient format and store it in canonical form.
+	pubKey, err = crypto.NormalizeRecipient(pubKey)
 	if err != nil {
-		return fmt.Errorf("failed to load manifest: %w", err)
+		return fmt.Errorf("invalid public key format: %w", err)
 	}
 
 	if err := manifest.AddUser(alias, pubKey); err != nil {
 		return fmt.Errorf("failed to add user: %w", err)
 	}
+	if err := manifest.SetRole(alias, role); err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if err := manifest.SetExpiry(alias, expires); err != nil {
+		return fmt.Errorf("failed to set expiry: %w", err)
+	}
+	for _, g := range groups {
+		if err := manifest.AddGroupMembers(strings.TrimSpace(g), alias); err != nil {
+			return fmt.Errorf("failed to add %s to group %s: %w", alias, g, err)
+		}
+	}
 
 	// 2. Save to disk FIRST to guarantee data consistency
 	if err := deps.ManifestStore.Save(manifest); err != nil {
@@ -123,10 +168,15 @@ func resolvePublicKey(cmd *cobra.Command, args []string) (string, func(), error)
 
 func printUsersAddSuccess(cmd *cobra.Command, alias string) {
 	green := color.New(color.FgGreen).SprintFunc()
+
+	cmd.Printf("%s User %q added to manifest.\n", green("✓"), alias)
+	printPendingRekey(cmd)
+}
+
+func printPendingRekey(cmd *cobra.Command) {
 	yellow := color.New(color.FgYellow).SprintFunc()
 	bold := color.New(color.Bold).SprintFunc()
 
-	cmd.Printf("%s User %q added to manifest.\n", green("✓"), alias)
 	cmd.Println()
 	cmd.Println(yellow("⚠️  PENDING ACTION:"))
 	cmd.Printf("The user is listed, but %s access yet.\n", bold("DOES NOT HAVE"))
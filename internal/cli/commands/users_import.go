@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+const (
+	formatAuto           = "auto"
+	formatAuthorizedKeys = "authorized_keys"
+	formatAllowedSigners = "allowed_signers"
+)
+
+var aliasInvalidCharsRe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// importedKey is one usable public key found in an import file.
+type importedKey struct {
+	line   int
+	alias  string
+	pubKey string
+}
+
+func newUsersImportCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Add users from an authorized_keys or allowed_signers file",
+		Long: `Bulk-adds SSH public keys (ssh-ed25519 / ssh-rsa) to envseal.yaml.
+
+Supported formats:
+  authorized_keys  [options] keytype base64 [comment]   alias taken from the comment
+  allowed_signers  principals [options] keytype base64  alias taken from the first principal
+
+The alias is the part before '@'. Keys already in the manifest are skipped.
+With "auto", files named *allowed_signers* are read as allowed_signers and
+everything else as authorized_keys. Use "-" to read from stdin.
+
+As with 'users add', run 'envseal-cli rekey' afterwards to grant access.`,
+		Example: `  envseal users import team_authorized_keys
+  envseal users import .git/allowed_signers
+  curl -s https://github.com/alice.keys | envseal users import - --name alice`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUsersImport(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().String("format", formatAuto, "Input format: auto, authorized_keys or allowed_signers")
+	cmd.Flags().String("name", "", "Use this alias for every key instead of deriving it (suffixed -2, -3... when repeated)")
+	cmd.Flags().Bool("dry-run", false, "Show which users would be added without saving")
+	return cmd
+}
+
+func runUsersImport(cmd *cobra.Command, args []string, deps Deps) error {
+	format, _ := cmd.Flags().GetString("format")
+	nameOverride, _ := cmd.Flags().GetString("name")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	path := args[0]
+	data, err := readImportSource(cmd, path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatAuto:
+		format = formatAuthorizedKeys
+		if strings.Contains(filepath.Base(path), formatAllowedSigners) {
+			format = formatAllowedSigners
+		}
+	case formatAuthorizedKeys, formatAllowedSigners:
+	default:
+		return fmt.Errorf("unknown format %q (use %s, %s or %s)", format, formatAuto, formatAuthorizedKeys, formatAllowedSigners)
+	}
+
+	nameOverride = strings.TrimSpace(nameOverride)
+	if nameOverride != "" && !aliasRe.MatchString(nameOverride) {
+		return fmt.Errorf("invalid alias %q (allowed: letters, numbers, '_', '.', '-', 2-64 chars)", nameOverride)
+	}
+
+	keys, problems := parseSSHKeyList(data, format, nameOverride)
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	if err := requireAdmin(deps, manifest); err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	added := 0
+	for _, k := range keys {
+		if existing, ok := manifest.FindUserByPublicKey(k.pubKey); ok {
+			problems = append(problems, fmt.Sprintf("line %d: key already in manifest as %q", k.line, existing.Name))
+			continue
+		}
+
+		alias := uniqueAlias(manifest, k.alias)
+		if err := manifest.AddUser(alias, k.pubKey); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", k.line, err))
+			continue
+		}
+		cmd.Printf("%s %s (%s)\n", green("✓"), alias, strings.Fields(k.pubKey)[0])
+		added++
+	}
+
+	for _, p := range problems {
+		cmd.Printf("%s skipped %s\n", yellow("•"), p)
+	}
+
+	if added == 0 {
+		cmd.Println("No users added.")
+		return nil
+	}
+
+	if dryRun {
+		cmd.Println()
+		cmd.Println(yellow(fmt.Sprintf("Dry run: %d user(s) would be added.", added)))
+		return nil
+	}
+
+	if err := deps.ManifestStore.Save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	cmd.Printf("\n%s %d user(s) added to manifest.\n", green("✓"), added)
+	printPendingRekey(cmd)
+	return nil
+}
+
+func readImportSource(cmd *cobra.Command, path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// parseSSHKeyList extracts usable keys from an authorized_keys or
+// allowed_signers file. Lines that cannot be used are described in problems.
+func parseSSHKeyList(data []byte, format, nameOverride string) (keys []importedKey, problems []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var principal string
+		if format == formatAllowedSigners {
+			principal, line, _ = strings.Cut(line, " ")
+			principal, _, _ = strings.Cut(strings.Trim(principal, `"`), ",")
+		}
+
+		pubKey, comment, err := crypto.ParseSSHPublicKey(line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", n, err))
+			continue
+		}
+
+		alias := nameOverride
+		if alias == "" {
+			source := comment
+			if format == formatAllowedSigners {
+				source = principal
+			}
+			alias = aliasFromIdentifier(source)
+		}
+		if alias == "" {
+			problems = append(problems, fmt.Sprintf("line %d: cannot derive an alias (use --name)", n))
+			continue
+		}
+
+		keys = append(keys, importedKey{line: n, alias: alias, pubKey: pubKey})
+	}
+
+	return keys, problems
+}
+
+// aliasFromIdentifier turns "alice@example.com" or "alice@laptop" into "alice".
+// It returns "" if no valid alias can be derived.
+func aliasFromIdentifier(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "@")
+	s = aliasInvalidCharsRe.ReplaceAllString(s, "-")
+	s = strings.Trim(s, "-_.")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	if !aliasRe.MatchString(s) {
+		return ""
+	}
+	return s
+}
+
+// uniqueAlias appends -2, -3... to alias until no manifest user has that name.
+func uniqueAlias(m *config.Manifest, alias string) string {
+	taken := make(map[string]struct{}, len(m.AccessControl))
+	for _, u := range m.AccessControl {
+		taken[u.Name] = struct{}{}
+	}
+
+	candidate := alias
+	for i := 2; ; i++ {
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", alias, i)
+	}
+}
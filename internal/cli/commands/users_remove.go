@@ -6,6 +6,8 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
 )
 
 func newUsersRemoveCommand(deps Deps) *cobra.Command {
@@ -36,6 +38,12 @@ func runUsersRemove(cmd *cobra.Command, args []string, deps Deps) error {
 	if err != nil {
 		return fmt.Errorf("failed to load manifest: %w", err)
 	}
+	if err := requireAdmin(deps, manifest); err != nil {
+		return err
+	}
+	if manifest.IsLastAdmin(identifier) {
+		return fmt.Errorf("cannot remove %q: %w", identifier, config.ErrLastAdmin)
+	}
 
 	// Prefer strict remove if available, to keep behavior explicit and testable.
 	// Fallback to bool-based RemoveUser if you haven't added RemoveUserStrict.
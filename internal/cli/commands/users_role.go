@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+func newUsersRoleCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "role <alias> <admin|member>",
+		Short: "Promote a user to admin or demote them to member",
+		Long: `Sets the role of a user in envseal.yaml.
+
+Admins manage the access list and rekey vaults. The last admin cannot be
+demoted; promote someone else first.`,
+		Example: `  envseal users role jane admin
+  envseal users role bob member`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUsersRole(cmd, args, deps)
+		},
+	}
+	return cmd
+}
+
+func runUsersRole(cmd *cobra.Command, args []string, deps Deps) error {
+	alias := strings.TrimSpace(args[0])
+	role := strings.ToLower(strings.TrimSpace(args[1]))
+
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	if err := requireAdmin(deps, manifest); err != nil {
+		return err
+	}
+
+	if err := manifest.SetRole(alias, role); err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+
+	if err := deps.ManifestStore.Save(manifest); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	cmd.Printf("%s %s is now %s in %s.\n", color.GreenString("✓"), alias, role, config.ManifestFileName)
+	return nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/config"
+)
+
+var errVerifyFailed = errors.New("verification failed")
+
+func NewVerifyCommand(deps Deps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [FILE...]",
+		Short: "Check that every value in a vault is encrypted and intact",
+		Long: `Decrypts and authenticates every value in the given vaults (default: the
+active vault) and exits non-zero if any value is corrupt or stored unencrypted.
+
+With --no-decrypt no identity is needed: only the ENC[...] wrapping of each
+value is checked, which is useful on CI runners without access to the vault.`,
+		Example: `  envseal verify
+  envseal verify secrets.dev.enc.yaml secrets.prod.enc.yaml
+  envseal verify --no-decrypt`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(cmd, args, deps)
+		},
+	}
+
+	cmd.Flags().Bool("no-decrypt", false, "Only check that values are encrypted (no identity required)")
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string, deps Deps) error {
+	noDecrypt, err := cmd.Flags().GetBool("no-decrypt")
+	if err != nil {
+		return err
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths = []string{secretFilePath}
+	}
+
+	var identities []age.Identity
+	if !noDecrypt {
+		identities, err = deps.IdentityManager.Load(identityFilePath)
+		if err != nil {
+			return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+		}
+	}
+
+	failed := false
+	for _, path := range paths {
+		cmd.Printf("🔎 Verifying %s\n", path)
+
+		sf, err := deps.SecretsStore.Load(path)
+		if err == nil && !noDecrypt {
+			_, err = sf.Unlock(identities...)
+		}
+		if err != nil {
+			cmd.Printf("  %s %v\n", color.RedString("✗"), err)
+			failed = true
+			continue
+		}
+
+		if !verifySecretFile(cmd, sf, noDecrypt) {
+			failed = true
+		}
+		sf.Lock()
+	}
+
+	cmd.Println()
+	if failed {
+		cmd.Println(color.RedString("❌ Verification failed."))
+		return errVerifyFailed
+	}
+
+	cmd.Println(color.GreenString("✅ All values are valid."))
+	return nil
+}
+
+// verifySecretFile prints the findings for one vault and reports whether it passed.
+func verifySecretFile(cmd *cobra.Command, sf *config.SecretFile, noDecrypt bool) bool {
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var corrupt, unencrypted []string
+	var hint string
+
+	if noDecrypt {
+		unencrypted = sf.UnencryptedKeys()
+		if len(unencrypted) == 0 {
+			cmd.Printf("  %s every value is encrypted\n", green("✓"))
+		}
+	} else {
+		all, invalid, err := sf.GetAllSecretsLenient()
+		if err != nil {
+			cmd.Printf("  %s %v\n", red("✗"), err)
+			return false
+		}
+		valid := len(all)
+		if invalid != nil {
+			corrupt, unencrypted, hint = invalid.Corrupt, invalid.Unencrypted, invalid.Hint
+			valid -= len(unencrypted)
+		}
+		cmd.Printf("  %s %d value(s) decrypted and authenticated\n", green("✓"), valid)
+		if locked := sf.LockedKeys(); len(locked) > 0 {
+			cmd.Printf("  %s %d value(s) not checked: not a member of their group (%s)\n", yellow("•"), len(locked), strings.Join(locked, ", "))
+		}
+	}
+
+	if len(corrupt) > 0 {
+		cmd.Printf("  %s corrupt or tampered: %s\n", red("✗"), strings.Join(corrupt, ", "))
+		if hint != "" {
+			cmd.Printf("    (%s)\n", hint)
+		}
+	}
+	if len(unencrypted) > 0 {
+		cmd.Printf("  %s stored unencrypted: %s\n", red("✗"), strings.Join(unencrypted, ", "))
+	}
+	if legacy := sf.LegacyEncodedKeys(); len(legacy) > 0 && sf.Version() < config.FormatVersion {
+		cmd.Printf("  %s %d value(s) use the legacy v1 encoding (run 'envseal migrate')\n", yellow("⚠"), len(legacy))
+	}
+
+	return len(corrupt) == 0 && len(unencrypted) == 0
+}
@@ -2,36 +2,152 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
 )
 
 func NewWhoamiCommand(deps Deps) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "whoami",
-		Short: "Show your local identity",
-		Long:  "Displays your public key (Age format). Share this key with a project administrator to get access.",
-		Args:  cobra.NoArgs,
+		Short: "Show your local identities",
+		Long: `Displays your public keys (Age or SSH format). Share a key with a project administrator to get access.
+
+When several identities are available (a multi-key identity file or
+~/.envseal/identities/), all of them are listed, along with the vaults of
+the current project each one can open.
+
+With --generate, an identity is created first if none exists yet (a
+post-quantum hybrid one with --pq), without touching the current directory.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runWhoami(cmd, deps)
 		},
 	}
+
+	cmd.Flags().Bool("generate", false, "Create an identity if none exists yet")
+	cmd.Flags().Bool("pq", false, "With --generate, create a post-quantum hybrid (ML-KEM-768 + X25519) identity")
+	return cmd
 }
 
 func runWhoami(cmd *cobra.Command, deps Deps) error {
-	identity, err := deps.IdentityManager.Load(identityFilePath)
+	generate, err := cmd.Flags().GetBool("generate")
 	if err != nil {
-		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+		return err
+	}
+	postQuantum, err := cmd.Flags().GetBool("pq")
+	if err != nil {
+		return err
 	}
 
 	bold := color.New(color.Bold).SprintFunc()
 	cyan := color.New(color.FgCyan).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	// Only generate when the file is missing, so an existing protected
+	// identity is not unlocked twice.
+	path := identityFile(identityFilePath)
+	if _, statErr := os.Stat(path); generate && path != "" && os.IsNotExist(statErr) {
+		if _, _, err := ensureIdentity(cmd, deps, newIdentityOptions{postQuantum: postQuantum}); err != nil {
+			return err
+		}
+		cmd.Println(green("✓ Identity created at " + path))
+	}
+
+	identities, err := deps.IdentityManager.Load(identityFilePath)
+	if err != nil {
+		return fmt.Errorf("identity error (run 'envseal-cli init' first?): %w", err)
+	}
+
+	pubKeys := make([]string, 0, len(identities))
+	for _, id := range identities {
+		pubKey, err := crypto.IdentityRecipient(id)
+		if err != nil {
+			return err
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	project, vaults := projectVaultRecipients(deps)
+
+	if len(pubKeys) == 1 {
+		cmd.Println("👋 Your Identity:")
+	} else {
+		cmd.Printf("👋 Your Identities (%d):\n", len(pubKeys))
+	}
+
+	for i, pubKey := range pubKeys {
+		if len(pubKeys) > 1 {
+			label := ""
+			if i == 0 {
+				label = " (primary)"
+			}
+			cmd.Printf("\n%d.%s\n", i+1, label)
+		}
+		cmd.Println(cyan(pubKey))
+
+		switch {
+		case crypto.IsPostQuantumRecipient(pubKey):
+			cmd.Println(green("Key type: post-quantum hybrid (ML-KEM-768 + X25519)"))
+		case crypto.IsPluginRecipient(pubKey):
+			cmd.Println("Key type: age plugin (handled by an external age-plugin-* binary)")
+		default:
+			cmd.Println(yellow("Key type: classical (not protected against future quantum attacks)"))
+		}
+
+		if project != "" {
+			var opens []string
+			for _, v := range vaults {
+				if slices.Contains(v.recipients, pubKey) {
+					opens = append(opens, v.path)
+				}
+			}
+			if len(opens) == 0 {
+				cmd.Printf("Opens in %s: %s\n", project, yellow("nothing yet"))
+			} else {
+				cmd.Printf("Opens in %s: %s\n", project, strings.Join(opens, ", "))
+			}
+		}
+	}
 
-	cmd.Println("👋 Your Identity:")
-	cmd.Println(cyan(identity.Recipient().String()))
 	cmd.Println()
-	cmd.Println(bold("Next step:"), "Send this key to your project administrator.")
+	cmd.Println(bold("Next step:"), "Send your key to your project administrator.")
 
 	return nil
 }
+
+type vaultRecipients struct {
+	path       string
+	recipients []string
+}
+
+// projectVaultRecipients returns the project name of the manifest in the
+// current directory (or "" outside a project) and who each of its vaults is
+// encrypted for. Only the recipient headers are read; nothing is decrypted.
+func projectVaultRecipients(deps Deps) (string, []vaultRecipients) {
+	manifest, err := deps.ManifestStore.Load()
+	if err != nil {
+		return "", nil
+	}
+
+	var vaults []vaultRecipients
+	for _, path := range projectVaultPaths(manifest) {
+		sf, err := deps.SecretsStore.Load(path)
+		if err != nil {
+			continue
+		}
+		recipients, err := sf.GetRecipients()
+		if err != nil {
+			continue
+		}
+		vaults = append(vaults, vaultRecipients{path: path, recipients: recipients})
+	}
+
+	return manifest.ProjectName, vaults
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// Environment is a vault with its own access list, e.g. production secrets
+// that only some users may read. An environment without users or groups is
+// open to every user.
+type Environment struct {
+	Name   string   `yaml:"name"`
+	File   string   `yaml:"file"`
+	Users  []string `yaml:"users,omitempty"`
+	Groups []string `yaml:"groups,omitempty"`
+}
+
+// FindEnvironment returns the environment called name.
+func (m *Manifest) FindEnvironment(name string) (Environment, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.Environments {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Environment{}, false
+}
+
+// EnvironmentFiles returns the vault path of every environment.
+func (m *Manifest) EnvironmentFiles() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := make([]string, 0, len(m.Environments))
+	for _, e := range m.Environments {
+		files = append(files, e.File)
+	}
+	return files
+}
+
+// EnvironmentForFile returns the environment whose vault is path.
+func (m *Manifest) EnvironmentForFile(path string) (Environment, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.Environments {
+		if filepath.Clean(e.File) == filepath.Clean(path) {
+			return e, true
+		}
+	}
+	return Environment{}, false
+}
+
+// VaultPublicKeys returns the recipients of group in the vault at path: the
+// group's members, restricted to the users allowed in the vault's
+// environment if it has one. The recovery key is always included.
+func (m *Manifest) VaultPublicKeys(path, group string) ([]string, error) {
+	keys, err := m.GroupPublicKeys(group)
+	if err != nil {
+		return nil, err
+	}
+
+	env, ok := m.EnvironmentForFile(path)
+	if !ok || (len(env.Users) == 0 && len(env.Groups) == 0) {
+		return keys, nil
+	}
+
+	allowed, err := m.environmentPublicKeys(env)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(k string) bool {
+		return k != m.RecoveryKey && !slices.Contains(allowed, k)
+	}), nil
+}
+
+// environmentPublicKeys returns the public keys of the users of env and of
+// the members of its groups.
+func (m *Manifest) environmentPublicKeys(env Environment) ([]string, error) {
+	var keys []string
+	for _, group := range env.Groups {
+		groupKeys, err := m.GroupPublicKeys(group)
+		if err != nil {
+			return nil, fmt.Errorf("environment %q: %w", env.Name, err)
+		}
+		keys = append(keys, groupKeys...)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range env.Users {
+		i := slices.IndexFunc(m.AccessControl, func(u User) bool { return u.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("environment %q: %w: %q", env.Name, ErrUserNotFound, name)
+		}
+		keys = append(keys, m.AccessControl[i].PublicKey)
+	}
+	return keys, nil
+}
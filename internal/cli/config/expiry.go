@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExpiryWarningPeriod is how long before their expiry users are reported as
+// expiring soon.
+const ExpiryWarningPeriod = 14 * 24 * time.Hour
+
+const expiryDateLayout = "2006-01-02"
+
+var ErrInvalidExpiry = errors.New("invalid expiry (use YYYY-MM-DD or an RFC 3339 time)")
+
+// ParseExpiry parses an expires_at value. A date alone means the end of that
+// day, UTC, so access lasts through the given day.
+func ParseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(expiryDateLayout, s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, ErrInvalidExpiry
+}
+
+// Expiry returns when the access of u ends, and false if it never does.
+func (u User) Expiry() (time.Time, bool) {
+	if u.ExpiresAt == "" {
+		return time.Time{}, false
+	}
+	t, err := ParseExpiry(u.ExpiresAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Expired reports whether the access of u has ended at now.
+func (u User) Expired(now time.Time) bool {
+	t, ok := u.Expiry()
+	return ok && !now.Before(t)
+}
+
+// ExpiresSoon reports whether the access of u ends within
+// ExpiryWarningPeriod of now, without having ended yet.
+func (u User) ExpiresSoon(now time.Time) bool {
+	t, ok := u.Expiry()
+	return ok && now.Before(t) && t.Sub(now) <= ExpiryWarningPeriod
+}
+
+// ExpiredUsers returns the users whose access has ended at now.
+func (m *Manifest) ExpiredUsers(now time.Time) []User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var expired []User
+	for _, u := range m.AccessControl {
+		if u.Expired(now) {
+			expired = append(expired, u)
+		}
+	}
+	return expired
+}
+
+// SetExpiry sets the expires_at of the user called name; an empty expiresAt
+// removes it.
+func (m *Manifest) SetExpiry(name, expiresAt string) error {
+	expiresAt = strings.TrimSpace(expiresAt)
+	if expiresAt != "" {
+		if _, err := ParseExpiry(expiresAt); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.AccessControl {
+		if m.AccessControl[i].Name == name {
+			m.AccessControl[i].ExpiresAt = expiresAt
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUserNotFound, name)
+}
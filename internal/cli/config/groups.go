@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// DefaultGroup holds every secret that is not tagged with another group. Its
+// DEK is the one listed under `_envseal.recipients`.
+const DefaultGroup = "default"
+
+var (
+	ErrInvalidGroup = errors.New("invalid group name (allowed: letters, numbers, '_', '.', '-', up to 64 chars)")
+
+	groupNameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+)
+
+// ValidGroupName reports whether name can be used as a group name.
+func ValidGroupName(name string) bool {
+	return groupNameRe.MatchString(name)
+}
+
+// metadataGroups returns the groups with a DEK in meta, DefaultGroup first
+// and the others sorted.
+func metadataGroups(meta Metadata) []string {
+	groups := make([]string, 0, len(meta.Groups)+1)
+	for g := range meta.Groups {
+		if g != DefaultGroup {
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	return append([]string{DefaultGroup}, groups...)
+}
+
+func groupRecipients(meta Metadata, group string) []Recipient {
+	if group == DefaultGroup {
+		return meta.Recipients
+	}
+	return meta.Groups[group]
+}
+
+// Groups returns the groups that have a DEK in the vault, DefaultGroup first.
+func (sf *SecretFile) Groups() []string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return nil
+	}
+	return metadataGroups(meta)
+}
+
+// HasGroup reports whether group has a DEK in the vault.
+func (sf *SecretFile) HasGroup(group string) bool {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return false
+	}
+	_, ok := meta.Groups[group]
+	return ok || group == DefaultGroup
+}
+
+// CanOpenGroup reports whether the DEK of group was unlocked.
+func (sf *SecretFile) CanOpenGroup(group string) bool {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.deks[group] != nil
+}
+
+// GroupRecipients returns the public keys the DEK of group is wrapped for.
+func (sf *SecretFile) GroupRecipients(group string) ([]string, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, r := range groupRecipients(meta, group) {
+		keys = append(keys, r.Arg)
+	}
+	return keys, nil
+}
+
+// RotateGroupRecipients wraps the DEK of group for publicKeys only. A group
+// without a DEK in the vault yet gets a new one; an existing group must have
+// been unlocked.
+func (sf *SecretFile) RotateGroupRecipients(group string, publicKeys []string) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if !ValidGroupName(group) {
+		return fmt.Errorf("%w: %q", ErrInvalidGroup, group)
+	}
+	if len(sf.deks) == 0 {
+		return errors.New("cannot rotate recipients without unlocking the file first")
+	}
+
+	if sf.deks[group] == nil {
+		meta, err := sf.metadataLocked()
+		if err != nil {
+			return err
+		}
+		if _, exists := meta.Groups[group]; exists || group == DefaultGroup {
+			return fmt.Errorf("%w (%s)", ErrGroupLocked, group)
+		}
+		dek, err := crypto.GenerateDEK()
+		if err != nil {
+			return err
+		}
+		sf.setDEKLocked(group, dek)
+	}
+
+	return sf.rotateRecipientsLocked(group, publicKeys)
+}
+
+// SecretGroups maps every secret to its group. It does not need the file to
+// be unlocked.
+func (sf *SecretFile) SecretGroups() map[string]string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	entries := sf.secretEntriesLocked()
+	out := make(map[string]string, len(entries))
+	for k, v := range entries {
+		out[k] = storedValueGroup(v)
+	}
+	return out
+}
+
+// LockedKeys returns the keys whose group was not unlocked, which
+// GetAllSecrets leaves out.
+func (sf *SecretFile) LockedKeys() []string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	var keys []string
+	for k, v := range sf.secretEntriesLocked() {
+		if sf.deks[storedValueGroup(v)] == nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -6,8 +6,21 @@ import (
 )
 
 const (
-	IdentityFileName = "identity"
-	Directory        = ".envseal"
+	IdentityFileName  = "identity"
+	IdentitiesDirName = "identities"
+	Directory         = ".envseal"
+
+	// PassphraseEnvVar, when set, provides the passphrase of a protected
+	// identity instead of prompting for it (CI, scripts).
+	PassphraseEnvVar = "ENVSEAL_PASSPHRASE"
+
+	// IdentitySourceEnvVar overrides the default --identity value, e.g.
+	// "env:ENVSEAL_IDENTITY" or "cmd:pass show envseal/key" on CI runners.
+	IdentitySourceEnvVar = "ENVSEAL_IDENTITY_SOURCE"
+
+	// RecoveryWordsEnvVar provides the words of the recovery key to
+	// --recovery-words instead of prompting for them.
+	RecoveryWordsEnvVar = "ENVSEAL_RECOVERY_WORDS"
 )
 
 // GetDefaultIdentityFilePath returns the default path to the identity file
@@ -20,3 +33,14 @@ func GetDefaultIdentityFilePath() (string, error) {
 
 	return filepath.Join(home, Directory, IdentityFileName), nil
 }
+
+// GetDefaultIdentitiesDir returns ~/.envseal/identities, whose files hold
+// additional identities used alongside the default identity file.
+func GetDefaultIdentitiesDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, Directory, IdentitiesDirName), nil
+}
@@ -3,29 +3,44 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/flootic/envseal/pkg/filesystem"
-	"gopkg.in/yaml.v3"
 )
 
 const ManifestFileName = "envseal.yaml"
 
 var (
-	ErrUserExists    = errors.New("a user with this public key already exists")
+	ErrUserExists    = errors.New("user already exists")
 	ErrUserNotFound  = errors.New("user not found")
 	ErrInvalidName   = errors.New("name cannot be empty")
 	ErrInvalidPubKey = errors.New("public key cannot be empty")
+	ErrGroupNotFound = errors.New("group not found")
+	ErrInvalidRole   = errors.New("invalid role (use admin or member)")
+	ErrLastAdmin     = errors.New("the manifest must keep at least one admin")
 )
 
 // User represents a user entry in the manifest.
 type User struct {
 	Name      string `yaml:"name"`
 	PublicKey string `yaml:"public_key"`
+	Role      string `yaml:"role,omitempty"` // RoleAdmin or RoleMember (empty)
+	// ExpiresAt ends the access of the user: a date (through that day, UTC)
+	// or an RFC 3339 time. Expired users are left out of every recipient list.
+	ExpiresAt string `yaml:"expires_at,omitempty"`
+}
+
+// Group is a named set of users who can read the secrets tagged with it.
+type Group struct {
+	Name    string   `yaml:"name"`
+	Members []string `yaml:"members"` // user names from access_control
 }
 
 // Manifest maps the structure of the envseal.yaml file.
@@ -37,6 +52,24 @@ type Manifest struct {
 
 	ProjectName   string `yaml:"project_name"`
 	AccessControl []User `yaml:"access_control"`
+	// Groups restrict secrets to some users. Untagged secrets are in the
+	// implicit DefaultGroup, which has every user unless it is declared here.
+	Groups []Group `yaml:"groups,omitempty"`
+	// Environments give vaults their own access list.
+	Environments []Environment `yaml:"environments,omitempty"`
+	// EncryptSecretMetadata stores the metadata of each secret (description,
+	// owner, timestamps...) encrypted like its value instead of in plain YAML.
+	EncryptSecretMetadata bool `yaml:"encrypt_secret_metadata,omitempty"`
+	// TrackSecretChanges records in the metadata of every secret when it was
+	// created and last updated, and by whom. Off, only secrets with a
+	// rotation policy are tracked, so setting a value is a one-line diff.
+	TrackSecretChanges bool `yaml:"track_secret_changes,omitempty"`
+	// RecoveryKey is the public key of the offline recovery key, a recipient
+	// of every vault alongside the users.
+	RecoveryKey string `yaml:"recovery_key,omitempty"`
+
+	// doc is the YAML the manifest was parsed from, which Save only patches.
+	doc *yamlDoc
 }
 
 // LoadManifest reads and parses the configuration file from disk.
@@ -52,24 +85,43 @@ func LoadManifest() (*Manifest, error) {
 		return nil, err
 	}
 
+	return ParseManifest(data)
+}
+
+// ParseManifest parses manifest content that did not necessarily come from
+// disk (e.g. the version staged in Git).
+func ParseManifest(data []byte) (*Manifest, error) {
 	var m Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
+	doc, err := parseYAMLDoc(data, &m)
+	if err != nil {
 		return nil, err
 	}
+	m.doc = doc
 
 	// Normalize after loading (trim fields, dedupe, stable ordering).
 	m.normalizeInPlace()
 
+	for _, u := range m.AccessControl {
+		if u.ExpiresAt == "" {
+			continue
+		}
+		if _, err := ParseExpiry(u.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("user %q: expires_at %q: %w", u.Name, u.ExpiresAt, err)
+		}
+	}
+
 	return &m, nil
 }
 
 // Save writes the manifest to disk with safe permissions (0600) using an atomic write.
+// Keys, comments and layout of the file it was loaded from are kept, only
+// the entries that changed are rewritten.
 func (m *Manifest) Save() error {
-	m.mu.RLock()
+	m.mu.Lock()
 	// Work on a copy to avoid holding the lock across marshaling I/O if desired.
 	// (Marshalling is pure CPU, but keeping it simple and safe.)
-	data, err := yaml.Marshal(m)
-	m.mu.RUnlock()
+	data, err := m.doc.marshal(m)
+	m.mu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -82,7 +134,9 @@ func (m *Manifest) Save() error {
 	return filesystem.AtomicWriteFile(ManifestFileName, data, 0o600)
 }
 
-// AddUser adds a user avoiding duplicate public keys.
+// AddUser adds a user avoiding duplicate names and public keys: roles,
+// groups and environments refer to users by name, so a second user with the
+// same name would take over the settings of the first.
 func (m *Manifest) AddUser(name, pubKey string) error {
 	name = strings.TrimSpace(name)
 	pubKey = strings.TrimSpace(pubKey)
@@ -98,8 +152,11 @@ func (m *Manifest) AddUser(name, pubKey string) error {
 	defer m.mu.Unlock()
 
 	for _, u := range m.AccessControl {
+		if u.Name == name {
+			return fmt.Errorf("%w: the name %q is taken", ErrUserExists, name)
+		}
 		if u.PublicKey == pubKey {
-			return ErrUserExists
+			return fmt.Errorf("%w: this public key belongs to %s", ErrUserExists, u.Name)
 		}
 	}
 
@@ -116,7 +173,8 @@ func (m *Manifest) AddUser(name, pubKey string) error {
 	return nil
 }
 
-// RemoveUser removes a user by name or public key.
+// RemoveUser removes a user by name or public key, and from every group and
+// environment.
 // Returns true if a user was removed.
 func (m *Manifest) RemoveUser(identifier string) bool {
 	identifier = strings.TrimSpace(identifier)
@@ -128,21 +186,29 @@ func (m *Manifest) RemoveUser(identifier string) bool {
 	defer m.mu.Unlock()
 
 	newUsers := make([]User, 0, len(m.AccessControl))
-	found := false
+	var removed []string
 
 	for _, u := range m.AccessControl {
 		if u.Name == identifier || u.PublicKey == identifier {
-			found = true
+			removed = append(removed, u.Name)
 			continue
 		}
 		newUsers = append(newUsers, u)
 	}
 
-	if found {
-		m.AccessControl = newUsers
+	if len(removed) == 0 {
+		return false
 	}
 
-	return found
+	m.AccessControl = newUsers
+	isRemoved := func(name string) bool { return slices.Contains(removed, name) }
+	for i := range m.Groups {
+		m.Groups[i].Members = slices.DeleteFunc(m.Groups[i].Members, isRemoved)
+	}
+	for i := range m.Environments {
+		m.Environments[i].Users = slices.DeleteFunc(m.Environments[i].Users, isRemoved)
+	}
+	return true
 }
 
 // RemoveUserStrict removes a user by name or public key and returns an error if not found.
@@ -153,22 +219,115 @@ func (m *Manifest) RemoveUserStrict(identifier string) error {
 	return nil
 }
 
-// GetPublicKeys extracts only the public keys (string slice) for cryptographic operations.
+// ReplacePublicKey gives the user holding oldKey the new public key newKey,
+// keeping their name. It fails if no user has oldKey or another one has newKey.
+func (m *Manifest) ReplacePublicKey(oldKey, newKey string) error {
+	oldKey = strings.TrimSpace(oldKey)
+	newKey = strings.TrimSpace(newKey)
+	if newKey == "" {
+		return ErrInvalidPubKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := -1
+	for i, u := range m.AccessControl {
+		switch u.PublicKey {
+		case newKey:
+			return fmt.Errorf("%w: this public key belongs to %s", ErrUserExists, u.Name)
+		case oldKey:
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return ErrUserNotFound
+	}
+
+	m.AccessControl[idx].PublicKey = newKey
+	return nil
+}
+
+// GetPublicKeys extracts only the public keys (string slice) for cryptographic operations,
+// including the recovery key if there is one and leaving out expired users.
 // The returned slice is a copy.
 func (m *Manifest) GetPublicKeys() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	keys := make([]string, 0, len(m.AccessControl))
+	now := time.Now()
+	keys := make([]string, 0, len(m.AccessControl)+1)
 	for _, u := range m.AccessControl {
-		if u.PublicKey == "" {
+		if u.PublicKey == "" || u.Expired(now) {
 			continue
 		}
 		keys = append(keys, u.PublicKey)
 	}
+	if m.RecoveryKey != "" && !slices.Contains(keys, m.RecoveryKey) {
+		keys = append(keys, m.RecoveryKey)
+	}
 	return keys
 }
 
+// GroupNames returns the declared groups other than DefaultGroup, sorted.
+func (m *Manifest) GroupNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.Groups))
+	for _, g := range m.Groups {
+		if g.Name != DefaultGroup {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+// GroupPublicKeys returns the public keys of the members of group, plus the
+// recovery key. DefaultGroup has every user unless it is declared. Expired
+// users are left out.
+func (m *Manifest) GroupPublicKeys(group string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var members []string
+	found := false
+	for _, g := range m.Groups {
+		if g.Name == group {
+			members, found = g.Members, true
+			break
+		}
+	}
+
+	now := time.Now()
+	var keys []string
+	switch {
+	case found:
+		for _, name := range members {
+			i := slices.IndexFunc(m.AccessControl, func(u User) bool { return u.Name == name })
+			if i < 0 {
+				return nil, fmt.Errorf("group %q: %w: %q", group, ErrUserNotFound, name)
+			}
+			if !m.AccessControl[i].Expired(now) {
+				keys = append(keys, m.AccessControl[i].PublicKey)
+			}
+		}
+	case group == DefaultGroup:
+		for _, u := range m.AccessControl {
+			if !u.Expired(now) {
+				keys = append(keys, u.PublicKey)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrGroupNotFound, group)
+	}
+
+	if m.RecoveryKey != "" && !slices.Contains(keys, m.RecoveryKey) {
+		keys = append(keys, m.RecoveryKey)
+	}
+	return keys, nil
+}
+
 // FindUserByPublicKey returns the user and true if found.
 func (m *Manifest) FindUserByPublicKey(pubKey string) (User, bool) {
 	pubKey = strings.TrimSpace(pubKey)
@@ -195,6 +354,11 @@ func (m *Manifest) normalizeInPlace() {
 	for _, u := range m.AccessControl {
 		u.Name = strings.TrimSpace(u.Name)
 		u.PublicKey = strings.TrimSpace(u.PublicKey)
+		u.Role = strings.ToLower(strings.TrimSpace(u.Role))
+		u.ExpiresAt = strings.TrimSpace(u.ExpiresAt)
+		if u.Role == RoleMember {
+			u.Role = ""
+		}
 
 		if u.PublicKey == "" {
 			// Ignore invalid entries rather than failing hard on load.
@@ -217,4 +381,21 @@ func (m *Manifest) normalizeInPlace() {
 	})
 
 	m.AccessControl = out
+	m.RecoveryKey = strings.TrimSpace(m.RecoveryKey)
+
+	for i := range m.Groups {
+		g := &m.Groups[i]
+		g.Name = strings.TrimSpace(g.Name)
+		for j := range g.Members {
+			g.Members[j] = strings.TrimSpace(g.Members[j])
+		}
+		sort.Strings(g.Members)
+		g.Members = slices.Compact(g.Members)
+	}
+	sort.Slice(m.Groups, func(i, j int) bool { return m.Groups[i].Name < m.Groups[j].Name })
+
+	for i := range m.Environments {
+		m.Environments[i].Name = strings.TrimSpace(m.Environments[i].Name)
+		m.Environments[i].File = strings.TrimSpace(m.Environments[i].File)
+	}
 }
@@ -0,0 +1,504 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+
+	"filippo.io/age"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// MergeReport lists what Merge did.
+type MergeReport struct {
+	// Theirs are the keys whose value or metadata came from the other side.
+	Theirs []string
+	// Reencrypted are the keys re-encrypted because their side used another
+	// group key than the merged vault.
+	Reencrypted []string
+	// Conflicts are the keys changed differently on both sides, and the
+	// keys one side still has in a group the other side removed. They keep
+	// our version, and the group is kept for them.
+	Conflicts []string
+	// Rewrapped are the groups whose recipients changed on both sides, and
+	// whose key is now wrapped for the merged list.
+	Rewrapped []string
+}
+
+// Merge applies to sf, our version of a vault, the changes theirs made
+// since base: a three-way merge of the recipients of every group, of the
+// secrets and of their metadata. A key changed on one side only, or the
+// same way on both, merges cleanly; a key changed differently on both sides
+// is a conflict and keeps our version.
+//
+// Values written by one side for a group key the merged vault does not use
+// (e.g. after 'rekey --rotate' on the other side) are re-encrypted. This,
+// and comparing keys changed on both sides, needs identities that can open
+// the groups involved; identities is only called when that happens.
+func (sf *SecretFile) Merge(base, theirs *SecretFile, identities func() ([]age.Identity, error)) (*MergeReport, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	base.mu.RLock()
+	defer base.mu.RUnlock()
+	theirs.mu.RLock()
+	defer theirs.mu.RUnlock()
+
+	m := &vaultMerger{
+		ours:       sf,
+		base:       base,
+		theirs:     theirs,
+		metas:      make(map[*SecretFile]Metadata),
+		deks:       make(map[*SecretFile]map[string][]byte),
+		sources:    make(map[string]*SecretFile),
+		resultDEKs: make(map[string][]byte),
+		identities: identities,
+		report:     &MergeReport{},
+	}
+	defer m.wipe()
+
+	for _, side := range []*SecretFile{sf, base, theirs} {
+		meta, err := side.metadataLocked()
+		if err != nil && !errors.Is(err, ErrMissingMetadata) {
+			return nil, err
+		}
+		m.metas[side] = meta
+	}
+
+	if err := m.mergeMetadata(); err != nil {
+		return nil, err
+	}
+	if err := m.mergeSecrets(); err != nil {
+		return nil, err
+	}
+	return m.report, nil
+}
+
+type vaultMerger struct {
+	ours, base, theirs *SecretFile
+
+	metas map[*SecretFile]Metadata
+	// result is the merged metadata, once mergeMetadata is done.
+	result Metadata
+	// sources tells, for every group, whose key the merged vault keeps.
+	sources map[string]*SecretFile
+	// resultDEKs are the keys of the groups wrapped again by the merge.
+	resultDEKs map[string][]byte
+	deks       map[*SecretFile]map[string][]byte
+
+	identities func() ([]age.Identity, error)
+	loaded     []age.Identity
+	report     *MergeReport
+}
+
+func (m *vaultMerger) wipe() {
+	for _, deks := range m.deks {
+		for _, dek := range deks {
+			zeroBytes(dek)
+		}
+	}
+}
+
+// dek opens the key of group g in the version side of the vault.
+func (m *vaultMerger) dek(side *SecretFile, g string) ([]byte, error) {
+	if dek, ok := m.deks[side][g]; ok {
+		return dek, nil
+	}
+	recipients := groupRecipients(m.metas[side], g)
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("group %s has no key", g)
+	}
+	if m.loaded == nil {
+		ids, err := m.identities()
+		if err != nil {
+			return nil, fmt.Errorf("an identity is needed to merge group %s: %w", g, err)
+		}
+		m.loaded = ids
+	}
+	_, dek := unlockRecipients(recipients, m.loaded)
+	if dek == nil {
+		return nil, fmt.Errorf("%w (group %s)", ErrGroupLocked, g)
+	}
+	if m.deks[side] == nil {
+		m.deks[side] = make(map[string][]byte)
+	}
+	m.deks[side][g] = dek
+	return dek, nil
+}
+
+// resultDEK returns the key of group g in the merged vault.
+func (m *vaultMerger) resultDEK(g string) ([]byte, error) {
+	if dek, ok := m.resultDEKs[g]; ok {
+		return dek, nil
+	}
+	source, ok := m.sources[g]
+	if !ok {
+		return nil, fmt.Errorf("group %s is not in the merged vault", g)
+	}
+	return m.dek(source, g)
+}
+
+// mergeMetadata merges the recipients of every group. A group changed on
+// one side takes that side's entries as they are; a group changed on both
+// sides is wrapped again for the merged recipient list, keeping the key of
+// the side that rotated it, if any.
+func (m *vaultMerger) mergeMetadata() error {
+	ours, base, theirs := m.metas[m.ours], m.metas[m.base], m.metas[m.theirs]
+
+	result := ours
+	result.Groups = maps.Clone(ours.Groups)
+	result.Version = max(ours.Version, theirs.Version)
+
+	groups := metadataGroups(ours)
+	for _, g := range metadataGroups(theirs) {
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+
+	rewrap := make(map[string][]string)
+	for _, g := range groups {
+		rA, rB, rO := groupRecipients(ours, g), groupRecipients(theirs, g), groupRecipients(base, g)
+		switch {
+		case slices.Equal(rA, rB), slices.Equal(rB, rO):
+			m.sources[g] = m.ours
+		case slices.Equal(rA, rO):
+			setGroupRecipients(&result, g, rB)
+			m.sources[g] = m.theirs
+		case len(rB) == 0:
+			// Removed by them but changed by us: keep it.
+			m.sources[g] = m.ours
+		case len(rA) == 0:
+			setGroupRecipients(&result, g, rB)
+			m.sources[g] = m.theirs
+		default:
+			source, dek, err := m.rotatedDEK(g)
+			if err != nil {
+				return err
+			}
+			m.sources[g] = source
+			m.resultDEKs[g] = dek
+			rewrap[g] = mergeRecipientKeys(rO, rA, rB)
+			m.report.Rewrapped = append(m.report.Rewrapped, g)
+		}
+		if len(groupRecipients(result, g)) == 0 && len(rewrap[g]) == 0 {
+			delete(m.sources, g)
+		}
+	}
+
+	// Recovery shares split the default key: take theirs only along with it.
+	switch {
+	case m.sources[DefaultGroup] == m.theirs && m.resultDEKs[DefaultGroup] == nil:
+		result.Recovery = theirs.Recovery
+	case reflect.DeepEqual(ours.Recovery, base.Recovery) &&
+		slices.Equal(groupRecipients(ours, DefaultGroup), groupRecipients(theirs, DefaultGroup)):
+		result.Recovery = theirs.Recovery
+	}
+
+	m.ours.RawData[MetadataKey] = result
+	for _, g := range slices.Sorted(maps.Keys(rewrap)) {
+		m.ours.setDEKLocked(g, cloneBytes(m.resultDEKs[g]))
+		if err := m.ours.rotateRecipientsLocked(g, rewrap[g]); err != nil {
+			return fmt.Errorf("failed to wrap the key of group %s: %w", g, err)
+		}
+	}
+
+	var err error
+	m.result, err = m.ours.metadataLocked()
+	return err
+}
+
+// rotatedDEK picks the key of group g when both sides changed its
+// recipients: theirs if only they rotated it, ours otherwise.
+func (m *vaultMerger) rotatedDEK(g string) (*SecretFile, []byte, error) {
+	dA, err := m.dek(m.ours, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	dB, err := m.dek(m.theirs, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(dA, dB) {
+		if dO, err := m.dek(m.base, g); err == nil && bytes.Equal(dA, dO) {
+			return m.theirs, dB, nil
+		}
+	}
+	return m.ours, dA, nil
+}
+
+func setGroupRecipients(meta *Metadata, g string, recipients []Recipient) {
+	if g == DefaultGroup {
+		meta.Recipients = recipients
+		return
+	}
+	if len(recipients) == 0 {
+		delete(meta.Groups, g)
+		return
+	}
+	if meta.Groups == nil {
+		meta.Groups = make(map[string][]Recipient)
+	}
+	meta.Groups[g] = recipients
+}
+
+// mergeRecipientKeys merges recipient lists by public key: keys added on
+// either side are kept, keys removed on either side are dropped.
+func mergeRecipientKeys(base, ours, theirs []Recipient) []string {
+	args := func(rs []Recipient) []string {
+		out := make([]string, len(rs))
+		for i, r := range rs {
+			out[i] = r.Arg
+		}
+		return out
+	}
+	o, a, b := args(base), args(ours), args(theirs)
+
+	var keys []string
+	for _, k := range append(a, b...) {
+		inA, inB, inO := slices.Contains(a, k), slices.Contains(b, k), slices.Contains(o, k)
+		if inO && (!inA || !inB) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return normalizeAndDedupe(keys)
+}
+
+// mergeSecrets merges the values and metadata of every key, then makes sure
+// every value is encrypted with the merged vault's key of its group.
+func (m *vaultMerger) mergeSecrets() error {
+	eO, eA, eB := m.base.secretEntriesLocked(), m.ours.secretEntriesLocked(), m.theirs.secretEntriesLocked()
+
+	secrets, err := m.ours.ensureSecretsMap(true)
+	if err != nil {
+		return err
+	}
+	metaO, err := m.base.ensureTopLevelMap(SecretsMetaKey, false)
+	if err != nil {
+		return err
+	}
+	metaB, err := m.theirs.ensureTopLevelMap(SecretsMetaKey, false)
+	if err != nil {
+		return err
+	}
+	metaA, err := m.ours.ensureTopLevelMap(SecretsMetaKey, true)
+	if err != nil {
+		return err
+	}
+
+	keys := slices.Collect(maps.Keys(eA))
+	for k := range eB {
+		if _, ok := eA[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vO, inO := eO[key]
+		vA, inA := eA[key]
+		vB, inB := eB[key]
+
+		origin := m.ours
+		switch {
+		case sameEntry(vA, inA, vB, inB), sameEntry(vB, inB, vO, inO):
+		case sameEntry(vA, inA, vO, inO):
+			origin = m.theirs
+		default:
+			// Every side has another ciphertext: both set the key, or one
+			// re-encrypted it with 'rekey --rotate'.
+			if origin = m.plainOrigin(m.ours.valueAD(key), vO, inO, vA, inA, vB, inB); origin == nil {
+				origin = m.ours
+				m.conflict(key)
+			}
+		}
+
+		if origin == m.theirs {
+			m.report.Theirs = append(m.report.Theirs, key)
+			if inB {
+				secrets[key] = vB
+			} else {
+				delete(secrets, key)
+				if m.ours.allowsLegacyLayoutLocked() && !isReservedKey(key) {
+					delete(m.ours.RawData, key)
+				}
+			}
+		}
+
+		if v, ok := secrets[key]; ok {
+			if g := storedValueGroup(v); m.groupRemoved(g) {
+				// The other side removed the group: keep it rather than lose
+				// the value, and let the user decide.
+				m.restoreGroup(origin, g)
+				m.conflict(key)
+			}
+			nv, changed, err := m.reencrypt(origin, v, m.ours.valueAD(key))
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if changed {
+				secrets[key] = nv
+				m.report.Reencrypted = append(m.report.Reencrypted, key)
+			}
+		}
+
+		mO, hasO := metaO[key]
+		mA, hasA := metaA[key]
+		mB, hasB := metaB[key]
+		metaOrigin := m.ours
+		switch {
+		case sameEntry(mA, hasA, mB, hasB), sameEntry(mB, hasB, mO, hasO):
+		case sameEntry(mA, hasA, mO, hasO):
+			metaOrigin = m.theirs
+		default:
+			if metaOrigin = m.plainOrigin(m.ours.metaAD(key), mO, hasO, mA, hasA, mB, hasB); metaOrigin == nil {
+				metaOrigin = origin
+			}
+		}
+		if metaOrigin == m.theirs {
+			if hasB {
+				metaA[key] = mB
+			} else {
+				delete(metaA, key)
+			}
+			if origin != m.theirs {
+				m.report.Theirs = append(m.report.Theirs, key)
+			}
+		}
+
+		if _, ok := secrets[key]; !ok {
+			delete(metaA, key)
+		} else if mv, ok := metaA[key]; ok {
+			nv, changed, err := m.reencrypt(metaOrigin, mv, m.ours.metaAD(key))
+			if err != nil {
+				return fmt.Errorf("metadata of %s: %w", key, err)
+			}
+			metaA[key] = nv
+			if changed && !slices.Contains(m.report.Reencrypted, key) {
+				m.report.Reencrypted = append(m.report.Reencrypted, key)
+			}
+		}
+	}
+
+	if len(metaA) == 0 {
+		delete(m.ours.RawData, SecretsMetaKey)
+	}
+	return nil
+}
+
+func (m *vaultMerger) conflict(key string) {
+	if !slices.Contains(m.report.Conflicts, key) {
+		m.report.Conflicts = append(m.report.Conflicts, key)
+	}
+}
+
+// groupRemoved reports whether the merged vault has no key for group g.
+func (m *vaultMerger) groupRemoved(g string) bool {
+	_, ok := m.sources[g]
+	return !ok && m.resultDEKs[g] == nil
+}
+
+// restoreGroup puts group g back in the merged vault with the recipients side
+// has for it.
+func (m *vaultMerger) restoreGroup(side *SecretFile, g string) {
+	setGroupRecipients(&m.result, g, groupRecipients(m.metas[side], g))
+	m.ours.RawData[MetadataKey] = m.result
+	m.sources[g] = side
+}
+
+func sameEntry(a any, inA bool, b any, inB bool) bool {
+	return inA == inB && (!inA || reflect.DeepEqual(a, b))
+}
+
+// plainEntry is a stored value as plaintext, to compare the versions of a
+// key whose ciphertexts all differ.
+type plainEntry struct {
+	present bool
+	group   string
+	value   string
+}
+
+// plainOrigin merges a key by the plaintext of its three versions. It
+// returns the side whose version the merge keeps, or nil when both changed
+// it differently or a version cannot be decrypted.
+func (m *vaultMerger) plainOrigin(ad []byte, vO any, inO bool, vA any, inA bool, vB any, inB bool) *SecretFile {
+	pA, okA := m.plainEntry(m.ours, ad, vA, inA)
+	pB, okB := m.plainEntry(m.theirs, ad, vB, inB)
+	if !okA || !okB {
+		return nil
+	}
+	if pA == pB {
+		return m.ours
+	}
+	pO, okO := m.plainEntry(m.base, ad, vO, inO)
+	switch {
+	case !okO:
+		return nil
+	case pB == pO:
+		return m.ours
+	case pA == pO:
+		return m.theirs
+	}
+	return nil
+}
+
+// plainEntry decrypts v, a version of a key written by side.
+func (m *vaultMerger) plainEntry(side *SecretFile, ad []byte, v any, present bool) (plainEntry, bool) {
+	if !present {
+		return plainEntry{}, true
+	}
+	s, ok := v.(string)
+	if !ok || !isWrapped(s, encPrefix) {
+		return plainEntry{}, false
+	}
+	g := storedValueGroup(s)
+	dek, err := m.dek(side, g)
+	if err != nil {
+		return plainEntry{}, false
+	}
+	plain, err := decryptIfNeeded(s, dek, ad)
+	if err != nil {
+		return plainEntry{}, false
+	}
+	return plainEntry{present: true, group: g, value: plain}, true
+}
+
+// reencrypt returns v, written by side, encrypted with the merged vault's
+// key of its group, and whether that changed it.
+func (m *vaultMerger) reencrypt(side *SecretFile, v any, ad []byte) (any, bool, error) {
+	s, ok := v.(string)
+	if !ok || (!isWrapped(s, encPrefix) && !isWrapped(s, legacyEncPrefix)) {
+		return v, false, nil
+	}
+	g := storedValueGroup(s)
+	if slices.Equal(groupRecipients(m.metas[side], g), groupRecipients(m.result, g)) {
+		return v, false, nil
+	}
+
+	dek, err := m.dek(side, g)
+	if err != nil {
+		return nil, false, err
+	}
+	resultDEK, err := m.resultDEK(g)
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.Equal(dek, resultDEK) {
+		return v, false, nil
+	}
+
+	plain, err := decryptIfNeeded(s, dek, ad)
+	if err != nil {
+		return nil, false, err
+	}
+	cipherText, err := crypto.EncryptValue(plain, resultDEK, ad)
+	if err != nil {
+		return nil, false, err
+	}
+	return wrapEncrypted(g, cipherText), true, nil
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// mergeFixture is a base vault, from which each test derives our and
+// their versions before merging them.
+type mergeFixture struct {
+	path string
+	id   *age.X25519Identity
+	base []byte
+}
+
+func newMergeFixture(t *testing.T) *mergeFixture {
+	t.Helper()
+	sf, id := newTestVault(t, t.TempDir(), "secrets.enc.yaml", map[string]string{"A": "1", "B": "2", "C": "3"})
+	x25519 := id.(*age.X25519Identity)
+	if err := sf.RotateGroupRecipients("ops", []string{x25519.Recipient().String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetSecretInGroup("OPS", "o", "ops"); err != nil {
+		t.Fatal(err)
+	}
+	return &mergeFixture{path: sf.Path(), id: x25519, base: marshalTestVault(t, sf)}
+}
+
+func marshalTestVault(t *testing.T, sf *SecretFile) []byte {
+	t.Helper()
+	data, err := sf.doc.marshal(sf.RawData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// version returns the base vault, unlocked with identities.
+func (f *mergeFixture) version(t *testing.T, data []byte, identities ...age.Identity) *SecretFile {
+	t.Helper()
+	sf, err := ParseSecretFile(f.path, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) == 0 {
+		identities = []age.Identity{f.id}
+	}
+	if _, err := sf.Unlock(identities...); err != nil {
+		t.Fatal(err)
+	}
+	return sf
+}
+
+// merge merges theirs into ours and returns the report with the secrets of
+// the merged vault, as read back from its saved form.
+func (f *mergeFixture) merge(t *testing.T, ours, theirs *SecretFile, identities ...age.Identity) (*MergeReport, map[string]string) {
+	t.Helper()
+	if len(identities) == 0 {
+		identities = []age.Identity{f.id}
+	}
+	report, err := ours.Merge(f.version(t, f.base), theirs, func() ([]age.Identity, error) { return identities, nil })
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	merged := f.version(t, marshalTestVault(t, ours), identities...)
+	all, err := merged.GetAllSecrets()
+	if err != nil {
+		t.Fatalf("the merged vault does not decrypt: %v", err)
+	}
+	return report, all
+}
+
+func mustSet(t *testing.T, sf *SecretFile, key, value string) {
+	t.Helper()
+	if err := sf.SetSecret(key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkSecrets(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			t.Errorf("unexpected key %s = %q", k, got[k])
+		}
+	}
+}
+
+// rotateTestVault does what 'rekey --rotate' does: new keys for every group
+// and every value encrypted again.
+func rotateTestVault(t *testing.T, sf *SecretFile, recipients ...string) {
+	t.Helper()
+	all, err := sf.GetAllSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := sf.SecretGroups()
+	if err := sf.Init(recipients); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.RotateGroupRecipients("ops", recipients); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range all {
+		if err := sf.SetSecretInGroup(k, v, groups[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeChangedOnOneSide(t *testing.T) {
+	f := newMergeFixture(t)
+	ours, theirs := f.version(t, f.base), f.version(t, f.base)
+	mustSet(t, ours, "B", "ours")
+	mustSet(t, theirs, "A", "theirs")
+	if err := theirs.UnsetSecret("C"); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, theirs, "D", "new")
+
+	report, all := f.merge(t, ours, theirs)
+	checkSecrets(t, all, map[string]string{"A": "theirs", "B": "ours", "D": "new", "OPS": "o"})
+	if len(report.Conflicts) != 0 {
+		t.Errorf("conflicts: %v", report.Conflicts)
+	}
+	if want := []string{"A", "C", "D"}; !slices.Equal(report.Theirs, want) {
+		t.Errorf("Theirs = %v, want %v", report.Theirs, want)
+	}
+}
+
+func TestMergeSameChangeOnBothSides(t *testing.T) {
+	f := newMergeFixture(t)
+	ours, theirs := f.version(t, f.base), f.version(t, f.base)
+	// Same plaintext, but each side has its own ciphertext.
+	mustSet(t, ours, "A", "same")
+	mustSet(t, theirs, "A", "same")
+
+	report, all := f.merge(t, ours, theirs)
+	checkSecrets(t, all, map[string]string{"A": "same", "B": "2", "C": "3", "OPS": "o"})
+	if len(report.Conflicts) != 0 {
+		t.Errorf("conflicts: %v", report.Conflicts)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	f := newMergeFixture(t)
+	ours, theirs := f.version(t, f.base), f.version(t, f.base)
+	mustSet(t, ours, "A", "ours")
+	mustSet(t, theirs, "A", "theirs")
+	// Deleted on our side, modified on theirs.
+	if err := ours.UnsetSecret("C"); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, theirs, "C", "theirs")
+
+	report, all := f.merge(t, ours, theirs)
+	checkSecrets(t, all, map[string]string{"A": "ours", "B": "2", "OPS": "o"})
+	if want := []string{"A", "C"}; !slices.Equal(report.Conflicts, want) {
+		t.Errorf("Conflicts = %v, want %v", report.Conflicts, want)
+	}
+}
+
+func TestMergeAfterRotateOnOneSide(t *testing.T) {
+	for _, rotatedByUs := range []bool{false, true} {
+		f := newMergeFixture(t)
+		ours, theirs := f.version(t, f.base), f.version(t, f.base)
+		rotated, other := theirs, ours
+		if rotatedByUs {
+			rotated, other = ours, theirs
+		}
+		rotateTestVault(t, rotated, f.id.Recipient().String())
+		mustSet(t, other, "B", "changed")
+		if err := other.SetSecretInGroup("OPS", "changed", "ops"); err != nil {
+			t.Fatal(err)
+		}
+
+		report, all := f.merge(t, ours, theirs)
+		checkSecrets(t, all, map[string]string{"A": "1", "B": "changed", "C": "3", "OPS": "changed"})
+		if len(report.Conflicts) != 0 {
+			t.Errorf("rotated by us %v: conflicts %v", rotatedByUs, report.Conflicts)
+		}
+		if !rotatedByUs && !slices.Contains(report.Reencrypted, "B") {
+			t.Errorf("B was not re-encrypted with the rotated key: %v", report.Reencrypted)
+		}
+	}
+}
+
+func TestMergeRecipientsChangedOnBothSides(t *testing.T) {
+	f := newMergeFixture(t)
+	alice, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	me := f.id.Recipient().String()
+
+	ours, theirs := f.version(t, f.base), f.version(t, f.base)
+	if err := ours.RotateRecipients([]string{me, alice.Recipient().String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := theirs.RotateRecipients([]string{me, bob.Recipient().String()}); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, theirs, "A", "theirs")
+
+	report, all := f.merge(t, ours, theirs)
+	checkSecrets(t, all, map[string]string{"A": "theirs", "B": "2", "C": "3", "OPS": "o"})
+	if want := []string{DefaultGroup}; !slices.Equal(report.Rewrapped, want) {
+		t.Errorf("Rewrapped = %v, want %v", report.Rewrapped, want)
+	}
+
+	recipients, err := ours.GroupRecipients(DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []*age.X25519Identity{f.id, alice, bob} {
+		if !slices.Contains(recipients, id.Recipient().String()) {
+			t.Errorf("%s is not a recipient of the merged vault", id.Recipient())
+		}
+	}
+	merged := f.version(t, marshalTestVault(t, ours), bob)
+	if v, err := merged.GetSecret("B"); err != nil || v != "2" {
+		t.Errorf("a recipient added by theirs reads B = %q, %v", v, err)
+	}
+}
+
+func TestMergeGroupRemovedByTheirs(t *testing.T) {
+	f := newMergeFixture(t)
+	ours, theirs := f.version(t, f.base), f.version(t, f.base)
+	if err := ours.SetSecretInGroup("OPS_TOKEN", "t", "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	// They move the values of the group to the default one and drop it.
+	if err := theirs.SetSecretInGroup("OPS", "o", DefaultGroup); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := theirs.metadataLocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(meta.Groups, "ops")
+	theirs.RawData[MetadataKey] = meta
+
+	report, all := f.merge(t, ours, theirs)
+	checkSecrets(t, all, map[string]string{"A": "1", "B": "2", "C": "3", "OPS": "o", "OPS_TOKEN": "t"})
+	if want := []string{"OPS_TOKEN"}; !slices.Equal(report.Conflicts, want) {
+		t.Errorf("Conflicts = %v, want %v", report.Conflicts, want)
+	}
+	if !ours.HasGroup("ops") {
+		t.Error("the group of OPS_TOKEN was not kept")
+	}
+	if g := ours.SecretGroups()["OPS"]; g != DefaultGroup {
+		t.Errorf("OPS is in group %q, want %q", g, DefaultGroup)
+	}
+}
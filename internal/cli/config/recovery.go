@@ -0,0 +1,202 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+const (
+	// RecoverySharePrefix starts the text form of a decrypted recovery share.
+	RecoverySharePrefix = "ENVSEAL-SHARE-"
+
+	recoveryCheckLabel = "envseal/recovery"
+)
+
+var (
+	ErrNoRecovery      = errors.New("recovery is not set up for this vault")
+	ErrNotCustodian    = errors.New("none of your identities holds a recovery share for this vault")
+	ErrRecoveryFailed  = errors.New("shares do not reconstruct the vault key (not enough shares, or shares from another setup)")
+	ErrInvalidShareStr = errors.New("malformed recovery share")
+)
+
+// Recovery holds Shamir shares of the DEK ("break-glass" access). Any
+// Threshold of them, each decrypted by its custodian, rebuild the DEK.
+type Recovery struct {
+	Threshold int `yaml:"threshold"`
+	// Check is a known value encrypted with the DEK, used to validate a
+	// reconstructed key before it is trusted.
+	Check  string          `yaml:"check"`
+	Shares []RecoveryShare `yaml:"shares"`
+}
+
+// RecoveryShare is one share of the DEK, wrapped for its custodian.
+type RecoveryShare struct {
+	Custodian string `yaml:"custodian"`
+	Arg       string `yaml:"arg"` // Custodian public key
+	Enc       string `yaml:"enc"` // Share encrypted for Arg
+}
+
+// Custodian is a person trusted with one recovery share.
+type Custodian struct {
+	Name      string
+	PublicKey string
+}
+
+// SetupRecovery splits the DEK of the default group into one share per
+// custodian, threshold of which are needed to recover it, replacing any
+// previous setup. Other groups are not covered.
+func (sf *SecretFile) SetupRecovery(threshold int, custodians []Custodian) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	dek := sf.deks[DefaultGroup]
+	if dek == nil {
+		return ErrLocked
+	}
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return err
+	}
+
+	parts, err := crypto.SplitSecret(dek, len(custodians), threshold)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, p := range parts {
+			zeroBytes(p)
+		}
+	}()
+
+	check, err := crypto.EncryptValue(recoveryCheckLabel, dek, []byte(recoveryCheckLabel))
+	if err != nil {
+		return err
+	}
+
+	recovery := &Recovery{Threshold: threshold, Check: check}
+	for i, c := range custodians {
+		enc, err := crypto.EncryptKeyMaterial(parts[i], []string{c.PublicKey})
+		if err != nil {
+			return fmt.Errorf("failed to encrypt share for %s: %w", c.Name, err)
+		}
+		recovery.Shares = append(recovery.Shares, RecoveryShare{Custodian: c.Name, Arg: c.PublicKey, Enc: enc})
+	}
+
+	meta.Recovery = recovery
+	sf.RawData[MetadataKey] = meta
+	return nil
+}
+
+// Recovery returns the recovery setup of the vault, or nil if there is none.
+func (sf *SecretFile) Recovery() *Recovery {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return nil
+	}
+	return meta.Recovery
+}
+
+// RecoveryShare decrypts the share held by one of identities and returns
+// its custodian name and text form, to be handed over for a recovery.
+func (sf *SecretFile) RecoveryShare(identities ...age.Identity) (custodian, share string, err error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return "", "", err
+	}
+	if meta.Recovery == nil {
+		return "", "", ErrNoRecovery
+	}
+
+	// As in Unlock, try the shares recorded for our public keys first.
+	mine := make(map[string]bool, len(identities))
+	for _, id := range identities {
+		if pubKey, err := crypto.IdentityRecipient(id); err == nil {
+			mine[pubKey] = true
+		}
+	}
+	shares := slices.Clone(meta.Recovery.Shares)
+	slices.SortStableFunc(shares, func(a, b RecoveryShare) int {
+		switch {
+		case mine[a.Arg] && !mine[b.Arg]:
+			return -1
+		case mine[b.Arg] && !mine[a.Arg]:
+			return 1
+		}
+		return 0
+	})
+
+	for _, s := range shares {
+		part, err := crypto.DecryptKeyMaterial(s.Enc, identities...)
+		if err != nil {
+			continue
+		}
+		defer zeroBytes(part)
+		return s.Custodian, RecoverySharePrefix + base64.RawURLEncoding.EncodeToString(part), nil
+	}
+
+	return "", "", ErrNotCustodian
+}
+
+// UnlockWithShares rebuilds the default group DEK from decrypted recovery
+// shares (text form, see RecoveryShare) and unlocks the file with it if it
+// passes the check.
+func (sf *SecretFile) UnlockWithShares(shares []string) error {
+	parts := make([][]byte, 0, len(shares))
+	defer func() {
+		for _, p := range parts {
+			zeroBytes(p)
+		}
+	}()
+	for _, s := range shares {
+		encoded, ok := strings.CutPrefix(strings.TrimSpace(s), RecoverySharePrefix)
+		if !ok {
+			return ErrInvalidShareStr
+		}
+		part, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return ErrInvalidShareStr
+		}
+		parts = append(parts, part)
+	}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return err
+	}
+	if meta.Recovery == nil {
+		return ErrNoRecovery
+	}
+	if len(parts) < meta.Recovery.Threshold {
+		return fmt.Errorf("%d share(s) provided, %d required", len(parts), meta.Recovery.Threshold)
+	}
+
+	dek, err := crypto.CombineShares(parts)
+	if err != nil {
+		return err
+	}
+	check, err := crypto.DecryptValue(meta.Recovery.Check, dek, []byte(recoveryCheckLabel))
+	if err != nil || check != recoveryCheckLabel {
+		zeroBytes(dek)
+		return ErrRecoveryFailed
+	}
+
+	sf.setDEKLocked(DefaultGroup, dek)
+	return nil
+}
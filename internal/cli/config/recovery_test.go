@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// setupTestRecovery returns a saved vault with threshold recovery across n
+// custodians, and the text share of each of them.
+func setupTestRecovery(t *testing.T, threshold, n int) (path string, shares []string) {
+	t.Helper()
+	sf, _ := newTestVault(t, t.TempDir(), "secrets.enc.yaml", map[string]string{"DB_PASSWORD": "hunter2"})
+
+	var custodians []Custodian
+	var identities []age.Identity
+	for i := range n {
+		id, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		custodians = append(custodians, Custodian{Name: string(rune('a' + i)), PublicKey: id.Recipient().String()})
+		identities = append(identities, id)
+	}
+	if err := sf.SetupRecovery(threshold, custodians); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range identities {
+		_, share, err := sf.RecoveryShare(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares = append(shares, share)
+	}
+	if err := sf.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return sf.Path(), shares
+}
+
+func loadTestVault(t *testing.T, path string) *SecretFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, err := ParseSecretFile(path, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sf
+}
+
+func TestUnlockWithShares(t *testing.T) {
+	path, shares := setupTestRecovery(t, 2, 3)
+
+	for _, pair := range [][]string{{shares[0], shares[1]}, {shares[2], shares[0]}, shares} {
+		sf := loadTestVault(t, path)
+		if err := sf.UnlockWithShares(pair); err != nil {
+			t.Fatalf("UnlockWithShares: %v", err)
+		}
+		if v, err := sf.GetSecret("DB_PASSWORD"); err != nil || v != "hunter2" {
+			t.Fatalf("GetSecret after recovery = %q, %v", v, err)
+		}
+	}
+}
+
+func TestUnlockWithSharesDetectsBadShares(t *testing.T) {
+	path, shares := setupTestRecovery(t, 2, 3)
+
+	corrupt := func(share string) string {
+		part, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(share, RecoverySharePrefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		part[len(part)-1] ^= 0x01
+		return RecoverySharePrefix + base64.RawURLEncoding.EncodeToString(part)
+	}
+	_, otherSetup := setupTestRecovery(t, 2, 3)
+
+	tests := []struct {
+		name   string
+		shares []string
+		want   error
+	}{
+		{"corrupted share", []string{corrupt(shares[0]), shares[1]}, ErrRecoveryFailed},
+		{"share of another setup", []string{shares[0], otherSetup[1]}, ErrRecoveryFailed},
+		{"missing prefix", []string{strings.TrimPrefix(shares[0], RecoverySharePrefix), shares[1]}, ErrInvalidShareStr},
+		{"not base64", []string{RecoverySharePrefix + "!!", shares[1]}, ErrInvalidShareStr},
+		{"below threshold", shares[:1], nil},
+	}
+	for _, tt := range tests {
+		sf := loadTestVault(t, path)
+		err := sf.UnlockWithShares(tt.shares)
+		if err == nil {
+			t.Errorf("%s: UnlockWithShares succeeded", tt.name)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		if sf.IsUnlocked() {
+			t.Errorf("%s: the vault was unlocked", tt.name)
+		}
+	}
+}
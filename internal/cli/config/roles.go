@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Roles of the users in the manifest. Only admins may change the access list
+// or rekey vaults; a manifest without any admin leaves this open to everyone,
+// as before roles existed.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ValidRole reports whether role is RoleAdmin or RoleMember.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember
+}
+
+// IsAdmin reports whether u has RoleAdmin.
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Admins returns the users with RoleAdmin.
+func (m *Manifest) Admins() []User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var admins []User
+	for _, u := range m.AccessControl {
+		if u.IsAdmin() {
+			admins = append(admins, u)
+		}
+	}
+	return admins
+}
+
+// CanAdminister reports whether one of pubKeys belongs to an admin whose
+// access has not expired, or the manifest declares no admin at all.
+func (m *Manifest) CanAdminister(pubKeys ...string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	hasAdmins := false
+	for _, u := range m.AccessControl {
+		if !u.IsAdmin() {
+			continue
+		}
+		if slices.Contains(pubKeys, u.PublicKey) && !u.Expired(now) {
+			return true
+		}
+		hasAdmins = true
+	}
+	return !hasAdmins
+}
+
+// SetRole gives the user called name role. Demoting the last admin fails
+// with ErrLastAdmin.
+func (m *Manifest) SetRole(name, role string) error {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if role == RoleMember {
+		role = ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := slices.IndexFunc(m.AccessControl, func(u User) bool { return u.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %q", ErrUserNotFound, name)
+	}
+	if m.AccessControl[idx].IsAdmin() && role != RoleAdmin && m.adminCountLocked() == 1 {
+		return ErrLastAdmin
+	}
+	m.AccessControl[idx].Role = role
+	return nil
+}
+
+// IsLastAdmin reports whether the user called name or holding that public
+// key is the only admin.
+func (m *Manifest) IsLastAdmin(identifier string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.AccessControl {
+		if (u.Name == identifier || u.PublicKey == identifier) && u.IsAdmin() {
+			return m.adminCountLocked() == 1
+		}
+	}
+	return false
+}
+
+func (m *Manifest) adminCountLocked() int {
+	n := 0
+	for _, u := range m.AccessControl {
+		if u.IsAdmin() {
+			n++
+		}
+	}
+	return n
+}
+
+// UserGroups returns the declared groups the user called name is a member of.
+func (m *Manifest) UserGroups(name string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var groups []string
+	for _, g := range m.Groups {
+		if slices.Contains(g.Members, name) {
+			groups = append(groups, g.Name)
+		}
+	}
+	return groups
+}
+
+// AddGroupMembers adds users to group, declaring the group if needed.
+func (m *Manifest) AddGroupMembers(group string, names ...string) error {
+	if !ValidGroupName(group) {
+		return ErrInvalidGroup
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		if !slices.ContainsFunc(m.AccessControl, func(u User) bool { return u.Name == name }) {
+			return fmt.Errorf("%w: %q", ErrUserNotFound, name)
+		}
+	}
+
+	idx := slices.IndexFunc(m.Groups, func(g Group) bool { return g.Name == group })
+	if idx < 0 {
+		m.Groups = append(m.Groups, Group{Name: group})
+		slices.SortFunc(m.Groups, func(a, b Group) int { return strings.Compare(a.Name, b.Name) })
+		idx = slices.IndexFunc(m.Groups, func(g Group) bool { return g.Name == group })
+	}
+
+	g := &m.Groups[idx]
+	g.Members = append(g.Members, names...)
+	slices.Sort(g.Members)
+	g.Members = slices.Compact(g.Members)
+	return nil
+}
+
+// RemoveGroupMembers removes users from group. The group stays declared even
+// if it ends up empty, so it keeps restricting its secrets.
+func (m *Manifest) RemoveGroupMembers(group string, names ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := slices.IndexFunc(m.Groups, func(g Group) bool { return g.Name == group })
+	if idx < 0 {
+		return fmt.Errorf("%w: %q", ErrGroupNotFound, group)
+	}
+
+	g := &m.Groups[idx]
+	for _, name := range names {
+		if !slices.Contains(g.Members, name) {
+			return fmt.Errorf("%q is not a member of group %q", name, group)
+		}
+	}
+	g.Members = slices.DeleteFunc(g.Members, func(name string) bool { return slices.Contains(names, name) })
+	return nil
+}
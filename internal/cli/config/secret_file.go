@@ -1,11 +1,14 @@
 package config
 
 import (
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -21,16 +24,39 @@ const (
 	DefaultSecretFileName = "secrets.enc.yaml"
 	MetadataKey           = "_envseal"
 	SecretsKey            = "secrets"
-
-	encPrefix = "ENC[age,chacha20,"
-	encSuffix = "]"
+	SecretsMetaKey        = "secrets_meta"
+
+	// encPrefix marks the current (v2) value encoding, whose ciphertext is
+	// bound to the vault file name and the secret's key via associated data.
+	encPrefix = "ENC[age,chacha20v2,"
+	// legacyEncPrefix marks v1 values, encrypted without associated data.
+	legacyEncPrefix = "ENC[age,chacha20,"
+	encSuffix       = "]"
+
+	// groupTagPrefix starts the optional group segment of a v2 value,
+	// ENC[age,chacha20v2,group=<name>,<base64>]. Values without it belong
+	// to DefaultGroup.
+	groupTagPrefix = "group="
+
+	valueADLabel = "envseal/v2"
+
+	// FormatVersion is the newest vault layout this binary can read and write.
+	// Version 1 (files without a version field) allowed secrets at the top
+	// level and v1 value encodings; version 2 keeps every secret under
+	// `secrets:` in the v2 encoding. Use Migrate to upgrade older vaults.
+	FormatVersion       = 2
+	legacyFormatVersion = 1
 )
 
 var (
-	ErrLocked          = errors.New("file locked")
-	ErrKeyNotFound     = errors.New("key not found")
-	ErrAccessDenied    = errors.New("access denied: your private key is not in the recipients list")
-	ErrMissingMetadata = errors.New("corrupt or uninitialized file: missing _envseal block")
+	ErrLocked             = errors.New("file locked")
+	ErrKeyNotFound        = errors.New("key not found")
+	ErrAccessDenied       = errors.New("access denied: your private key is not in the recipients list")
+	ErrMissingMetadata    = errors.New("corrupt or uninitialized file: missing _envseal block")
+	ErrUnsupportedVersion = errors.New("unsupported vault format version")
+	ErrUnencryptedValue   = errors.New("value is stored unencrypted")
+	ErrGroupLocked        = errors.New("access denied: you are not a member of this secret's group")
+	ErrLegacyEncoding     = errors.New("value uses the legacy v1 encoding, which a version 2 vault does not accept")
 )
 
 // Recipient represents a single entry in the access control list.
@@ -41,7 +67,52 @@ type Recipient struct {
 
 // Metadata defines the structure of the metadata block in the secret file.
 type Metadata struct {
-	Recipients []Recipient `yaml:"recipients"`
+	Version int `yaml:"version,omitempty"`
+	// Recipients hold the DEK of DefaultGroup, Groups the DEK of every other
+	// group, each wrapped only for that group's members.
+	Recipients []Recipient            `yaml:"recipients"`
+	Groups     map[string][]Recipient `yaml:"groups,omitempty"`
+	Recovery   *Recovery              `yaml:"recovery,omitempty"`
+}
+
+// InvalidSecretsError lists every secret that cannot be safely decrypted.
+type InvalidSecretsError struct {
+	Corrupt     []string // ENC[...] values that fail to decrypt or authenticate
+	Unencrypted []string // values stored without the ENC[...] wrapper
+	// Hint explains corrupt values that may only be bound to another file
+	// name.
+	Hint string
+}
+
+func (e *InvalidSecretsError) Error() string {
+	var parts []string
+	if len(e.Corrupt) > 0 {
+		parts = append(parts, "corrupt: "+strings.Join(e.Corrupt, ", "))
+	}
+	if len(e.Unencrypted) > 0 {
+		parts = append(parts, "unencrypted: "+strings.Join(e.Unencrypted, ", "))
+	}
+	msg := fmt.Sprintf("%d invalid secret(s) (%s)", len(e.Corrupt)+len(e.Unencrypted), strings.Join(parts, "; "))
+	if len(e.Corrupt) > 0 && e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+// MigrationReport lists the changes made by Migrate.
+type MigrationReport struct {
+	FromVersion int
+	ToVersion   int
+	Moved       []string // legacy top-level entries moved under `secrets:`
+	Encrypted   []string // moved entries that were stored as plaintext
+	Dropped     []string // legacy entries shadowed by a `secrets:` entry of the same name
+	Upgraded    []string // values re-encrypted from the v1 to the v2 encoding
+}
+
+// Changed reports whether the migration modified the file.
+func (r *MigrationReport) Changed() bool {
+	return r.FromVersion != r.ToVersion ||
+		len(r.Moved) > 0 || len(r.Dropped) > 0 || len(r.Upgraded) > 0
 }
 
 // SecretFile represents the file loaded in memory.
@@ -51,12 +122,16 @@ type SecretFile struct {
 	// RawData contains the entire YAML content (Metadata + Secrets + any extra keys).
 	RawData map[string]any
 
-	// decryptedDEK is the 32-byte master key, present only after Unlock()/Init().
-	// Unexported to reduce accidental exposure (logging, json/yaml dumps, etc.).
-	decryptedDEK []byte
+	// deks holds the 32-byte key of every group the caller could open, present
+	// only after Unlock()/Init(). Unexported to reduce accidental exposure
+	// (logging, json/yaml dumps, etc.).
+	deks map[string][]byte
 
 	// File path on disk
 	path string
+
+	// doc is the YAML the file was parsed from, which Save only patches.
+	doc *yamlDoc
 }
 
 // NewSecretFile creates an empty structure ready to initialize.
@@ -79,73 +154,185 @@ func LoadSecretFile(path string) (*SecretFile, error) {
 		return nil, err
 	}
 
+	return ParseSecretFile(path, data)
+}
+
+// ParseSecretFile parses vault content that did not necessarily come from
+// disk (e.g. the version staged in Git). path is the file the content
+// belongs to: it is part of the associated data of every value.
+func ParseSecretFile(path string, data []byte) (*SecretFile, error) {
 	raw := make(map[string]any)
-	if err := yaml.Unmarshal(data, &raw); err != nil {
+	doc, err := parseYAMLDoc(data, &raw)
+	if err != nil {
 		return nil, err
 	}
 
 	sf := &SecretFile{
 		path:    path,
 		RawData: raw,
+		doc:     doc,
 	}
+
+	if _, ok := raw[MetadataKey]; ok {
+		meta, err := sf.metadataLocked()
+		if err != nil {
+			return nil, err
+		}
+		if meta.Version > FormatVersion {
+			return nil, fmt.Errorf("%w: %s is version %d, this binary supports up to %d (upgrade envseal)",
+				ErrUnsupportedVersion, path, meta.Version, FormatVersion)
+		}
+	}
+
 	_, _ = sf.ensureSecretsMap(true)
 	return sf, nil
 }
 
-// IsUnlocked indicates whether the file currently holds a DEK in memory.
+// Path returns the file path the vault is loaded from and saved to.
+func (sf *SecretFile) Path() string {
+	return sf.path
+}
+
+// Version returns the format version recorded in the metadata block.
+// Files written before versioning was introduced report version 1.
+func (sf *SecretFile) Version() int {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.versionLocked()
+}
+
+func (sf *SecretFile) versionLocked() int {
+	meta, err := sf.metadataLocked()
+	if err != nil || meta.Version == 0 {
+		return legacyFormatVersion
+	}
+	return meta.Version
+}
+
+// allowsLegacyLayoutLocked reports whether top-level entries must still be
+// treated as secrets (version 1 files only).
+func (sf *SecretFile) allowsLegacyLayoutLocked() bool {
+	return sf.versionLocked() <= legacyFormatVersion
+}
+
+// allowsLegacyEncodingLocked reports whether values in the v1 encoding, which
+// is not bound to the key name, can still be read (version 1 files only).
+// Once a vault is migrated, an old value copied back from history under
+// another name must not decrypt.
+func (sf *SecretFile) allowsLegacyEncodingLocked() bool {
+	return sf.versionLocked() <= legacyFormatVersion
+}
+
+// IsUnlocked indicates whether the file currently holds a DEK in memory,
+// for at least one group.
 func (sf *SecretFile) IsUnlocked() bool {
 	sf.mu.RLock()
 	defer sf.mu.RUnlock()
-	return sf.decryptedDEK != nil
+	return len(sf.deks) > 0
 }
 
-// Lock wipes the DEK from memory and locks the file.
+// Lock wipes the DEKs from memory and locks the file.
 func (sf *SecretFile) Lock() {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
 
-	zeroBytes(sf.decryptedDEK)
-	sf.decryptedDEK = nil
+	sf.lockLocked()
+}
+
+func (sf *SecretFile) lockLocked() {
+	for _, dek := range sf.deks {
+		zeroBytes(dek)
+	}
+	sf.deks = nil
+}
+
+// setDEKLocked stores the key of group, replacing any previous one securely.
+func (sf *SecretFile) setDEKLocked(group string, dek []byte) {
+	if sf.deks == nil {
+		sf.deks = make(map[string][]byte)
+	}
+	zeroBytes(sf.deks[group])
+	sf.deks[group] = dek
 }
 
-// Unlock attempts to obtain the DEK by decrypting one of the recipient entries.
-func (sf *SecretFile) Unlock(identity *age.X25519Identity) error {
-	if identity == nil {
-		return errors.New("identity is nil")
+// Unlock obtains the DEK of every group that one of the given identities is a
+// recipient of, and returns the identity that opened the first of them. It
+// fails with ErrAccessDenied only if no group can be opened.
+func (sf *SecretFile) Unlock(identities ...age.Identity) (age.Identity, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("no identity provided")
 	}
 
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
 
-	metaInterface, ok := sf.RawData[MetadataKey]
-	if !ok {
-		return ErrMissingMetadata
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return nil, err
 	}
 
-	metaBytes, err := yaml.Marshal(metaInterface)
-	if err != nil {
-		return fmt.Errorf("error encoding metadata: %w", err)
+	var opener age.Identity
+	for _, group := range metadataGroups(meta) {
+		identity, dek := unlockRecipients(groupRecipients(meta, group), identities)
+		if identity == nil {
+			continue
+		}
+		sf.setDEKLocked(group, dek)
+		if opener == nil {
+			opener = identity
+		}
 	}
 
-	var meta Metadata
-	if err := yaml.Unmarshal(metaBytes, &meta); err != nil {
-		return fmt.Errorf("error parsing metadata: %w", err)
+	if opener == nil {
+		return nil, ErrAccessDenied
 	}
+	return opener, nil
+}
 
-	for _, recipient := range meta.Recipients {
-		dek, err := crypto.DecryptDEK(recipient.Enc, identity)
-		if err == nil {
-			// Replace any previous key securely.
-			zeroBytes(sf.decryptedDEK)
-			sf.decryptedDEK = cloneBytes(dek)
-			return nil
+// unlockRecipients returns the DEK wrapped in recipients and the identity
+// that decrypted it, or a nil identity if none of identities can.
+func unlockRecipients(recipients []Recipient, identities []age.Identity) (age.Identity, []byte) {
+	// Try each identity on the entry recorded for it only: plugin identities
+	// run an external binary, possibly waiting for a hardware touch, on every
+	// attempt. Identities without a known public key, or whose key matches no
+	// entry (an entry written by hand, say), fall back to trying all, after
+	// every identity that has a match.
+	type attempt struct {
+		identity age.Identity
+		enc      string
+	}
+	args := make([]string, len(recipients))
+	for i, r := range recipients {
+		args[i] = normalizeRecipientArg(r.Arg)
+	}
+	var preferred, others []attempt
+	for _, identity := range identities {
+		if identity == nil {
+			continue
+		}
+		pubKey, _ := crypto.IdentityRecipient(identity)
+		if pubKey != "" {
+			if i := slices.Index(args, normalizeRecipientArg(pubKey)); i >= 0 {
+				preferred = append(preferred, attempt{identity, recipients[i].Enc})
+				continue
+			}
+		}
+		for _, r := range recipients {
+			others = append(others, attempt{identity, r.Enc})
 		}
 	}
 
-	return ErrAccessDenied
+	for _, a := range append(preferred, others...) {
+		if dek, err := crypto.DecryptDEK(a.enc, a.identity); err == nil {
+			return a.identity, cloneBytes(dek)
+		}
+	}
+	return nil, nil
 }
 
 // Init initializes a new file by generating a new DEK and setting recipients.
+// Keys of other groups and recovery shares of the previous DEK no longer
+// apply and are removed; RotateGroupRecipients creates fresh group keys.
 func (sf *SecretFile) Init(initialRecipients []string) error {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
@@ -159,26 +346,30 @@ func (sf *SecretFile) Init(initialRecipients []string) error {
 		return err
 	}
 
-	// Replace any previous key securely.
-	zeroBytes(sf.decryptedDEK)
-	sf.decryptedDEK = cloneBytes(dek)
+	sf.lockLocked()
+	sf.setDEKLocked(DefaultGroup, cloneBytes(dek))
 
 	_, _ = sf.ensureSecretsMap(true)
-	return sf.rotateRecipientsLocked(initialRecipients)
+	if err := sf.rotateRecipientsLocked(DefaultGroup, initialRecipients); err != nil {
+		return err
+	}
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return err
+	}
+	meta.Groups = nil
+	meta.Recovery = nil
+	sf.RawData[MetadataKey] = meta
+	return nil
 }
 
-// RotateRecipients updates who has access (rekey).
+// RotateRecipients updates who has access to the default group (rekey).
 func (sf *SecretFile) RotateRecipients(publicKeys []string) error {
-	sf.mu.Lock()
-	defer sf.mu.Unlock()
-
-	if sf.decryptedDEK == nil {
-		return errors.New("cannot rotate recipients without unlocking the file first")
-	}
-	return sf.rotateRecipientsLocked(publicKeys)
+	return sf.RotateGroupRecipients(DefaultGroup, publicKeys)
 }
 
-func (sf *SecretFile) rotateRecipientsLocked(publicKeys []string) error {
+func (sf *SecretFile) rotateRecipientsLocked(group string, publicKeys []string) error {
 	publicKeys = normalizeAndDedupe(publicKeys)
 	if len(publicKeys) == 0 {
 		return errors.New("recipients list cannot be empty")
@@ -186,23 +377,68 @@ func (sf *SecretFile) rotateRecipientsLocked(publicKeys []string) error {
 
 	newRecipients := make([]Recipient, 0, len(publicKeys))
 	for _, pubKey := range publicKeys {
-		encDEK, err := crypto.EncryptDEK(sf.decryptedDEK, []string{pubKey})
+		encDEK, err := crypto.EncryptDEK(sf.deks[group], []string{pubKey})
 		if err != nil {
 			return fmt.Errorf("failed to encrypt for recipient %q: %w", pubKey, err)
 		}
 		newRecipients = append(newRecipients, Recipient{Arg: pubKey, Enc: encDEK})
 	}
 
-	sf.RawData[MetadataKey] = Metadata{Recipients: newRecipients}
+	meta, err := sf.metadataLocked()
+	if errors.Is(err, ErrMissingMetadata) && group == DefaultGroup {
+		// Brand new file: start at the current layout.
+		meta = Metadata{Version: FormatVersion}
+	} else if err != nil {
+		return err
+	}
+
+	if group == DefaultGroup {
+		meta.Recipients = newRecipients
+	} else {
+		if meta.Groups == nil {
+			meta.Groups = make(map[string][]Recipient)
+		}
+		meta.Groups[group] = newRecipients
+	}
+	sf.RawData[MetadataKey] = meta
 	return nil
 }
 
-// SetSecret encrypts a value and stores it under the canonical `secrets:` map.
+// metadataLocked decodes the `_envseal` block, whatever its in-memory form.
+func (sf *SecretFile) metadataLocked() (Metadata, error) {
+	metaInterface, ok := sf.RawData[MetadataKey]
+	if !ok {
+		return Metadata{}, ErrMissingMetadata
+	}
+	if meta, ok := metaInterface.(Metadata); ok {
+		return meta, nil
+	}
+
+	metaBytes, err := yaml.Marshal(metaInterface)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("error encoding metadata: %w", err)
+	}
+
+	var meta Metadata
+	if err := yaml.Unmarshal(metaBytes, &meta); err != nil {
+		return Metadata{}, fmt.Errorf("error parsing metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// SetSecret encrypts a value and stores it under the canonical `secrets:` map,
+// in the group the key already belongs to (DefaultGroup for new keys).
 func (sf *SecretFile) SetSecret(key, value string) error {
+	return sf.SetSecretInGroup(key, value, "")
+}
+
+// SetSecretInGroup is SetSecret with the key moved to group, whose DEK must
+// be unlocked. An empty group keeps the key's current group.
+func (sf *SecretFile) SetSecretInGroup(key, value, group string) error {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
 
-	if sf.decryptedDEK == nil {
+	if len(sf.deks) == 0 {
 		return ErrLocked
 	}
 
@@ -210,24 +446,40 @@ func (sf *SecretFile) SetSecret(key, value string) error {
 	if key == "" {
 		return errors.New("key cannot be empty")
 	}
-	if key == MetadataKey || key == SecretsKey {
+	if isReservedKey(key) {
 		return fmt.Errorf("cannot use reserved name %q", key)
 	}
 
-	encryptedVal, err := crypto.EncryptValue(value, sf.decryptedDEK)
+	secrets, err := sf.ensureSecretsMap(true)
 	if err != nil {
 		return err
 	}
 
-	secrets, err := sf.ensureSecretsMap(true)
+	if group == "" {
+		group = DefaultGroup
+		if v, ok := sf.secretEntriesLocked()[key]; ok {
+			group = storedValueGroup(v)
+		}
+	}
+	dek := sf.deks[group]
+	if dek == nil {
+		return ErrGroupLocked
+	}
+	if err := sf.moveSecretMetaLocked(key, group); err != nil {
+		return err
+	}
+
+	encryptedVal, err := crypto.EncryptValue(value, dek, sf.valueAD(key))
 	if err != nil {
 		return err
 	}
 
-	secrets[key] = wrapEncrypted(encryptedVal)
+	secrets[key] = wrapEncrypted(group, encryptedVal)
 
 	// Backwards-compat: if legacy top-level exists, keep canonical and remove legacy.
-	delete(sf.RawData, key)
+	if sf.allowsLegacyLayoutLocked() {
+		delete(sf.RawData, key)
+	}
 
 	return nil
 }
@@ -237,7 +489,7 @@ func (sf *SecretFile) UnsetSecret(key string) error {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
 
-	if sf.decryptedDEK == nil {
+	if len(sf.deks) == 0 {
 		return ErrLocked
 	}
 
@@ -245,7 +497,7 @@ func (sf *SecretFile) UnsetSecret(key string) error {
 	if key == "" {
 		return errors.New("key cannot be empty")
 	}
-	if key == MetadataKey || key == SecretsKey {
+	if isReservedKey(key) {
 		return fmt.Errorf("cannot unset reserved key %q", key)
 	}
 
@@ -256,22 +508,27 @@ func (sf *SecretFile) UnsetSecret(key string) error {
 
 	_, inSecrets := secrets[key]
 	_, inLegacy := sf.RawData[key]
+	inLegacy = inLegacy && sf.allowsLegacyLayoutLocked()
 	if !inSecrets && !inLegacy {
 		return fmt.Errorf("key %q does not exist", key)
 	}
 
 	delete(secrets, key)
-	delete(sf.RawData, key) // legacy
+	if inLegacy {
+		delete(sf.RawData, key)
+	}
+	sf.deleteSecretMetaLocked(key)
 	return nil
 }
 
 // GetSecret retrieves and decrypts a value.
-// It first checks `secrets:`, then falls back to legacy top-level keys.
+// It first checks `secrets:`, then falls back to legacy top-level keys in
+// version 1 files.
 func (sf *SecretFile) GetSecret(key string) (string, error) {
 	sf.mu.RLock()
 	defer sf.mu.RUnlock()
 
-	if sf.decryptedDEK == nil {
+	if len(sf.deks) == 0 {
 		return "", ErrLocked
 	}
 
@@ -279,129 +536,479 @@ func (sf *SecretFile) GetSecret(key string) (string, error) {
 	if key == "" {
 		return "", errors.New("key cannot be empty")
 	}
-	if key == MetadataKey || key == SecretsKey {
+	if isReservedKey(key) {
 		return "", fmt.Errorf("reserved key %q cannot be retrieved as a secret", key)
 	}
 
 	// Canonical location: secrets map
 	if secrets, err := sf.ensureSecretsMap(false); err == nil && secrets != nil {
 		if v, ok := secrets[key]; ok {
-			return sf.decryptAnyLocked(v)
+			return sf.decryptAnyLocked(key, v)
 		}
 	}
 
-	// Backwards-compat: legacy top-level secret
-	if v, ok := sf.RawData[key]; ok {
-		return sf.decryptAnyLocked(v)
+	// Backwards-compat: legacy top-level secret (version 1 files only)
+	if v, ok := sf.RawData[key]; ok && sf.allowsLegacyLayoutLocked() {
+		return sf.decryptAnyLocked(key, v)
 	}
 
 	return "", ErrKeyNotFound
 }
 
-func (sf *SecretFile) decryptAnyLocked(v any) (string, error) {
+// decryptAnyLocked decrypts a stored value with the DEK of its group,
+// refusing values that are not wrapped in ENC[...] so plaintext is never
+// mistaken for a decrypted secret.
+func (sf *SecretFile) decryptAnyLocked(key string, v any) (string, error) {
 	s, ok := v.(string)
 	if !ok {
 		return "", errors.New("value is not a string")
 	}
-	return decryptIfNeeded(s, sf.decryptedDEK)
+	if !isWrapped(s, encPrefix) && !isWrapped(s, legacyEncPrefix) {
+		return "", ErrUnencryptedValue
+	}
+	dek := sf.deks[storedValueGroup(s)]
+	if dek == nil {
+		return "", ErrGroupLocked
+	}
+	if isWrapped(s, legacyEncPrefix) {
+		if !sf.allowsLegacyEncodingLocked() {
+			return "", ErrLegacyEncoding
+		}
+		return decryptLegacy(s, dek)
+	}
+	plain, err := decryptIfNeeded(s, dek, sf.valueAD(key))
+	if err != nil {
+		return "", fmt.Errorf("%w (%s)", err, sf.bindingHint())
+	}
+	return plain, nil
+}
+
+// UpgradeEncoding re-encrypts every value still stored in the legacy v1
+// encoding (no associated data) into the current v2 encoding, in place.
+// It returns the upgraded keys in sorted order; call Save to persist them.
+func (sf *SecretFile) UpgradeEncoding() ([]string, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if sf.deks[DefaultGroup] == nil {
+		return nil, ErrLocked
+	}
+	return sf.upgradeEncodingLocked()
+}
+
+func (sf *SecretFile) upgradeEncodingLocked() ([]string, error) {
+	secrets, err := sf.ensureSecretsMap(true)
+	if err != nil {
+		return nil, err
+	}
+
+	// v1 values predate groups: they all belong to the default group.
+	dek := sf.deks[DefaultGroup]
+
+	var upgraded []string
+	upgrade := func(container map[string]any, key string, v any) error {
+		s, ok := v.(string)
+		if !ok || !isWrapped(s, legacyEncPrefix) {
+			return nil
+		}
+		if !sf.allowsLegacyEncodingLocked() {
+			return fmt.Errorf("%s: %w", key, ErrLegacyEncoding)
+		}
+		plain, err := decryptLegacy(s, dek)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+		encryptedVal, err := crypto.EncryptValue(plain, dek, sf.valueAD(key))
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %s: %w", key, err)
+		}
+		container[key] = wrapEncrypted(DefaultGroup, encryptedVal)
+		upgraded = append(upgraded, key)
+		return nil
+	}
+
+	for k, v := range secrets {
+		if err := upgrade(secrets, k, v); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range sf.RawData {
+		if isReservedKey(k) {
+			continue
+		}
+		if err := upgrade(sf.RawData, k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(upgraded)
+	return upgraded, nil
+}
+
+// Rebind re-encrypts the values and encrypted metadata written for a vault
+// named oldName (a file name or path) so they decrypt under the current file
+// name, after the vault was renamed or copied to start another environment.
+// Values already bound to the current name are left as they are. It returns
+// the rebound keys in sorted order; call Save to persist them.
+func (sf *SecretFile) Rebind(oldName string) ([]string, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if len(sf.deks) == 0 {
+		return nil, ErrLocked
+	}
+	oldName = filepath.Base(oldName)
+
+	secrets, err := sf.ensureSecretsMap(false)
+	if err != nil {
+		return nil, err
+	}
+	metas, err := sf.ensureTopLevelMap(SecretsMetaKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rebound := make(map[string]bool)
+	rebind := func(container map[string]any, key string, oldAD, newAD []byte) error {
+		s, ok := container[key].(string)
+		if !ok || !isWrapped(s, encPrefix) {
+			return nil // plaintext and v1 values are not bound to a name
+		}
+		group, cipherText := splitEncrypted(s)
+		dek := sf.deks[group]
+		if dek == nil {
+			return fmt.Errorf("%s: %w", key, ErrGroupLocked)
+		}
+		if _, err := crypto.DecryptValue(cipherText, dek, newAD); err == nil {
+			return nil
+		}
+		plain, err := crypto.DecryptValue(cipherText, dek, oldAD)
+		if err != nil {
+			return fmt.Errorf("%s does not decrypt as a value of %s either: %w", key, oldName, err)
+		}
+		encryptedVal, err := crypto.EncryptValue(plain, dek, newAD)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %s: %w", key, err)
+		}
+		container[key] = wrapEncrypted(group, encryptedVal)
+		rebound[key] = true
+		return nil
+	}
+
+	for _, k := range sortedKeys(secrets) {
+		if err := rebind(secrets, k, valueADFor(oldName, k), sf.valueAD(k)); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range sortedKeys(metas) {
+		if err := rebind(metas, k, metaADFor(oldName, k), sf.metaAD(k)); err != nil {
+			return nil, fmt.Errorf("metadata of %w", err)
+		}
+	}
+	return sortedKeys(rebound), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // Save writes the entire RawData map to disk (0600) using an atomic write.
+// Keys, comments and layout of the file it was loaded from are kept, only
+// the entries that changed are rewritten.
 func (sf *SecretFile) Save() error {
-	sf.mu.RLock()
-	data, err := yaml.Marshal(sf.RawData)
-	sf.mu.RUnlock()
+	return sf.SaveTo(sf.path)
+}
+
+// SaveTo writes the vault to path instead of the file it was loaded from,
+// e.g. the result of a Git merge. Values stay bound to the original name.
+func (sf *SecretFile) SaveTo(path string) error {
+	sf.mu.Lock()
+	data, err := sf.doc.marshal(sf.RawData)
+	sf.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(sf.path), 0o755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
 
-	return filesystem.AtomicWriteFile(sf.path, data, 0o600)
+	return filesystem.AtomicWriteFile(path, data, 0o600)
 }
 
-// GetRecipients returns the list of public keys currently embedded in the encrypted file header.
+// GetRecipients returns the list of public keys currently embedded in the
+// encrypted file header, i.e. every key that can open at least one group.
 func (sf *SecretFile) GetRecipients() ([]string, error) {
-	metaInterface, ok := sf.RawData[MetadataKey]
-	if !ok {
-		return nil, errors.New("missing _envseal metadata block")
-	}
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
 
-	metaBytes, _ := yaml.Marshal(metaInterface)
-	var meta Metadata
-	if err := yaml.Unmarshal(metaBytes, &meta); err != nil {
+	meta, err := sf.metadataLocked()
+	if err != nil {
 		return nil, err
 	}
 
 	var keys []string
-	for _, r := range meta.Recipients {
-		keys = append(keys, r.Arg)
+	for _, group := range metadataGroups(meta) {
+		for _, r := range groupRecipients(meta, group) {
+			keys = append(keys, r.Arg)
+		}
 	}
-	return keys, nil
+	return normalizeAndDedupe(keys), nil
 }
 
-// GetAllSecrets returns a map with all decrypted secrets.
-// It reads from `secrets:` and also includes legacy top-level entries (excluding reserved keys).
+// GetAllSecrets returns a map with all decrypted secrets the caller can open:
+// values of groups that were not unlocked are left out (see LockedKeys).
+// It reads from `secrets:` and, in version 1 files, also includes legacy
+// top-level entries (excluding reserved keys).
+//
+// It is strict: if any value is corrupt or stored without encryption, no
+// secrets are returned and the error is an *InvalidSecretsError listing them.
 func (sf *SecretFile) GetAllSecrets() (map[string]string, error) {
 	sf.mu.RLock()
 	defer sf.mu.RUnlock()
 
-	if sf.decryptedDEK == nil {
+	if len(sf.deks) == 0 {
 		return nil, ErrLocked
 	}
 
-	out := make(map[string]string)
+	out, invalid := sf.decryptAllLocked()
+	if invalid != nil {
+		return nil, invalid
+	}
+	return out, nil
+}
+
+// GetAllSecretsLenient is the non-strict variant of GetAllSecrets: values
+// stored without encryption are returned as-is and corrupt values are left
+// out. The skipped or suspicious keys are reported in the second result,
+// which is nil when every value is valid.
+func (sf *SecretFile) GetAllSecretsLenient() (map[string]string, *InvalidSecretsError, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	if len(sf.deks) == 0 {
+		return nil, nil, ErrLocked
+	}
+
+	out, invalid := sf.decryptAllLocked()
+	return out, invalid, nil
+}
+
+func (sf *SecretFile) decryptAllLocked() (map[string]string, *InvalidSecretsError) {
+	entries := sf.secretEntriesLocked()
+	out := make(map[string]string, len(entries))
+	invalid := &InvalidSecretsError{}
+
+	for k, v := range entries {
+		val, err := sf.decryptAnyLocked(k, v)
+		switch {
+		case errors.Is(err, ErrGroupLocked):
+			continue
+		case errors.Is(err, ErrUnencryptedValue):
+			invalid.Unencrypted = append(invalid.Unencrypted, k)
+			out[k] = v.(string)
+		case err != nil:
+			invalid.Corrupt = append(invalid.Corrupt, k)
+		default:
+			out[k] = val
+		}
+	}
+
+	if len(invalid.Corrupt) == 0 && len(invalid.Unencrypted) == 0 {
+		return out, nil
+	}
+	sort.Strings(invalid.Corrupt)
+	sort.Strings(invalid.Unencrypted)
+	if len(invalid.Corrupt) > 0 {
+		invalid.Hint = sf.bindingHint()
+	}
+	return out, invalid
+}
+
+// UnencryptedKeys returns the keys whose values are not wrapped in ENC[...].
+// It does not need the file to be unlocked.
+func (sf *SecretFile) UnencryptedKeys() []string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	var keys []string
+	for k, v := range sf.secretEntriesLocked() {
+		if s, ok := v.(string); ok && !isWrapped(s, encPrefix) && !isWrapped(s, legacyEncPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LegacyEncodedKeys returns the keys whose values still use the v1 encoding.
+// It does not need the file to be unlocked.
+func (sf *SecretFile) LegacyEncodedKeys() []string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	var keys []string
+	for k, v := range sf.secretEntriesLocked() {
+		if s, ok := v.(string); ok && isWrapped(s, legacyEncPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SecretFingerprints maps every secret to a short hash of its stored form,
+// which changes whenever the value is re-encrypted. It does not need the
+// file to be unlocked.
+func (sf *SecretFile) SecretFingerprints() map[string]string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	entries := sf.secretEntriesLocked()
+	out := make(map[string]string, len(entries))
+	for k, v := range entries {
+		sum := sha256.Sum256(fmt.Appendf(nil, "%v", v))
+		out[k] = hex.EncodeToString(sum[:4])
+	}
+	return out
+}
+
+// secretEntriesLocked returns every secret with its stored (encrypted) value:
+// the `secrets:` map plus, in version 1 files, legacy top-level string
+// entries. Canonical entries shadow legacy ones with the same name.
+func (sf *SecretFile) secretEntriesLocked() map[string]any {
+	out := make(map[string]any)
 
-	// Canonical secrets map
 	if secrets, err := sf.ensureSecretsMap(false); err == nil && secrets != nil {
 		for k, v := range secrets {
-			val, err := sf.decryptAnyLocked(v)
-			if err != nil {
-				if s, ok := v.(string); ok {
-					out[k] = s
-				} else {
-					out[k] = fmt.Sprintf("%v", v)
-				}
-				continue
-			}
-			out[k] = val
+			out[k] = v
 		}
 	}
 
+	if !sf.allowsLegacyLayoutLocked() {
+		return out
+	}
+
 	// Legacy top-level secrets (exclude reserved keys and nested maps)
 	for k, v := range sf.RawData {
-		if k == MetadataKey || k == SecretsKey {
+		if isReservedKey(k) {
 			continue
 		}
 		if _, already := out[k]; already {
 			continue
 		}
-		s, ok := v.(string)
-		if !ok {
+		if _, ok := v.(string); !ok {
 			continue
 		}
-		val, err := decryptIfNeeded(s, sf.decryptedDEK)
-		if err != nil {
-			out[k] = s
-			continue
+		out[k] = v
+	}
+
+	return out
+}
+
+// Migrate upgrades the file in place to FormatVersion: legacy top-level
+// entries are moved under `secrets:` (encrypting any plaintext ones), values
+// are re-encrypted into the v2 encoding and the version field is bumped.
+// Nothing is written to disk; call Save to persist the result.
+func (sf *SecretFile) Migrate() (*MigrationReport, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	dek := sf.deks[DefaultGroup]
+	if dek == nil {
+		return nil, ErrLocked
+	}
+
+	meta, err := sf.metadataLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{
+		FromVersion: sf.versionLocked(),
+		ToVersion:   FormatVersion,
+	}
+
+	secrets, err := sf.ensureSecretsMap(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if sf.allowsLegacyLayoutLocked() {
+		legacyKeys := make([]string, 0, len(sf.RawData))
+		for k, v := range sf.RawData {
+			if isReservedKey(k) {
+				continue
+			}
+			if _, ok := v.(string); !ok {
+				// Nested structures were never secrets; leave them alone.
+				continue
+			}
+			legacyKeys = append(legacyKeys, k)
+		}
+		sort.Strings(legacyKeys)
+
+		for _, k := range legacyKeys {
+			s := sf.RawData[k].(string)
+			if _, exists := secrets[k]; exists {
+				delete(sf.RawData, k)
+				report.Dropped = append(report.Dropped, k)
+				continue
+			}
+
+			var plain string
+			if isWrapped(s, legacyEncPrefix) {
+				plain, err = decryptLegacy(s, dek)
+			} else {
+				plain, err = decryptIfNeeded(s, dek, sf.valueAD(k))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt legacy entry %s: %w", k, err)
+			}
+			encryptedVal, err := crypto.EncryptValue(plain, dek, sf.valueAD(k))
+			if err != nil {
+				return nil, fmt.Errorf("failed to re-encrypt %s: %w", k, err)
+			}
+
+			if !isWrapped(s, encPrefix) && !isWrapped(s, legacyEncPrefix) {
+				report.Encrypted = append(report.Encrypted, k)
+			}
+			secrets[k] = wrapEncrypted(DefaultGroup, encryptedVal)
+			delete(sf.RawData, k)
+			report.Moved = append(report.Moved, k)
 		}
-		out[k] = val
 	}
 
-	return out, nil
+	if report.Upgraded, err = sf.upgradeEncodingLocked(); err != nil {
+		return nil, err
+	}
+
+	meta.Version = FormatVersion
+	sf.RawData[MetadataKey] = meta
+
+	return report, nil
 }
 
 // ensureSecretsMap returns the `secrets:` map, optionally creating it.
 func (sf *SecretFile) ensureSecretsMap(create bool) (map[string]any, error) {
-	raw, ok := sf.RawData[SecretsKey]
+	return sf.ensureTopLevelMap(SecretsKey, create)
+}
+
+// ensureTopLevelMap returns the top-level map stored under name, optionally
+// creating it.
+func (sf *SecretFile) ensureTopLevelMap(name string, create bool) (map[string]any, error) {
+	raw, ok := sf.RawData[name]
 	if !ok || raw == nil {
 		if !create {
 			return nil, nil
 		}
 		m := make(map[string]any)
-		sf.RawData[SecretsKey] = m
+		sf.RawData[name] = m
 		return m, nil
 	}
 
@@ -416,27 +1023,105 @@ func (sf *SecretFile) ensureSecretsMap(create bool) (map[string]any, error) {
 		for k, v := range legacy {
 			ks, ok := k.(string)
 			if !ok {
-				return nil, errors.New("invalid secrets format: non-string key")
+				return nil, fmt.Errorf("invalid %s format: non-string key", name)
 			}
 			converted[ks] = v
 		}
-		sf.RawData[SecretsKey] = converted
+		sf.RawData[name] = converted
 		return converted, nil
 	}
 
-	return nil, errors.New("invalid secrets format")
+	return nil, fmt.Errorf("invalid %s format", name)
+}
+
+// isReservedKey reports whether key is a top-level name used by envseal
+// itself, which cannot be a secret.
+func isReservedKey(key string) bool {
+	return key == MetadataKey || key == SecretsKey || key == SecretsMetaKey
+}
+
+// valueAD returns the associated data binding a v2 ciphertext to this vault
+// and to the key it is stored under, so blobs cannot be swapped between keys
+// or copied between vaults. Only the file name is used, so the binding does
+// not depend on the directory the CLI is run from.
+func (sf *SecretFile) valueAD(key string) []byte {
+	return valueADFor(filepath.Base(sf.path), key)
 }
 
-func wrapEncrypted(cipherText string) string {
+func valueADFor(vaultName, key string) []byte {
+	return []byte(valueADLabel + "\x00" + vaultName + "\x00" + key)
+}
+
+// bindingHint explains why a value of this vault may fail to decrypt
+// although the vault could be unlocked.
+func (sf *SecretFile) bindingHint() string {
+	return fmt.Sprintf("values only decrypt under the key and the file name (%q) they were written for; "+
+		"if this vault was renamed or copied from another file, run 'envseal rekey --renamed-from <old file name>'",
+		filepath.Base(sf.path))
+}
+
+func wrapEncrypted(group, cipherText string) string {
+	if group != DefaultGroup {
+		cipherText = groupTagPrefix + group + "," + cipherText
+	}
 	return encPrefix + cipherText + encSuffix
 }
 
-func decryptIfNeeded(value string, dek []byte) (string, error) {
-	if !strings.HasPrefix(value, encPrefix) || !strings.HasSuffix(value, encSuffix) {
+func isWrapped(value, prefix string) bool {
+	return strings.HasPrefix(value, prefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// splitEncrypted returns the group and ciphertext of a v2 value. The group
+// segment cannot be confused with the ciphertext: base64 never has '=' at
+// its sixth position followed by more data.
+func splitEncrypted(value string) (group, cipherText string) {
+	body := value[len(encPrefix) : len(value)-len(encSuffix)]
+	if tagged, ok := strings.CutPrefix(body, groupTagPrefix); ok {
+		if group, cipherText, ok := strings.Cut(tagged, ","); ok {
+			return group, cipherText
+		}
+	}
+	return DefaultGroup, body
+}
+
+// storedValueGroup returns the group of a stored value; anything but a
+// group-tagged v2 value belongs to DefaultGroup.
+func storedValueGroup(v any) string {
+	if s, ok := v.(string); ok && isWrapped(s, encPrefix) {
+		group, _ := splitEncrypted(s)
+		return group
+	}
+	return DefaultGroup
+}
+
+// decryptIfNeeded decrypts v2 values with ad. Legacy v1 values are refused:
+// only decryptLegacy reads them, for vaults that are still version 1.
+// Values that are not wrapped in ENC[...] are returned unchanged.
+func decryptIfNeeded(value string, dek []byte, ad []byte) (string, error) {
+	switch {
+	case isWrapped(value, encPrefix):
+		_, cipherText := splitEncrypted(value)
+		return crypto.DecryptValue(cipherText, dek, ad)
+	case isWrapped(value, legacyEncPrefix):
+		return "", ErrLegacyEncoding
+	default:
 		return value, nil
 	}
-	cipherText := value[len(encPrefix) : len(value)-len(encSuffix)]
-	return crypto.DecryptValue(cipherText, dek)
+}
+
+// decryptLegacy decrypts a v1 value, which has no associated data.
+func decryptLegacy(value string, dek []byte) (string, error) {
+	return crypto.DecryptValue(value[len(legacyEncPrefix):len(value)-len(encSuffix)], dek, nil)
+}
+
+// normalizeRecipientArg returns the form of a public key recipients are
+// compared in: trimmed, as normalizeAndDedupe stores it, and without the
+// comment of an SSH key.
+func normalizeRecipientArg(pubKey string) string {
+	if normalized, err := crypto.NormalizeRecipient(pubKey); err == nil {
+		return normalized
+	}
+	return strings.TrimSpace(pubKey)
 }
 
 func normalizeAndDedupe(keys []string) []string {
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+// newTestVault returns an unlocked vault at dir/name holding secrets, and the
+// identity that opens it.
+func newTestVault(t *testing.T, dir, name string, secrets map[string]string) (*SecretFile, age.Identity) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf := NewSecretFile(filepath.Join(dir, name))
+	if err := sf.Init([]string{id.Recipient().String()}); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range secrets {
+		if err := sf.SetSecret(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sf, id
+}
+
+func TestValueMovedToAnotherKeyIsRejected(t *testing.T) {
+	sf, _ := newTestVault(t, t.TempDir(), "secrets.enc.yaml", map[string]string{
+		"DB_PASSWORD": "hunter2",
+		"LOG_LEVEL":   "debug",
+	})
+
+	secrets, err := sf.ensureSecretsMap(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets["LOG_LEVEL"] = secrets["DB_PASSWORD"]
+
+	if v, err := sf.GetSecret("LOG_LEVEL"); err == nil {
+		t.Fatalf("a value copied from DB_PASSWORD decrypted as LOG_LEVEL: %q", v)
+	}
+	if v, err := sf.GetSecret("DB_PASSWORD"); err != nil || v != "hunter2" {
+		t.Fatalf("GetSecret(DB_PASSWORD) = %q, %v", v, err)
+	}
+}
+
+func TestValueMovedToAnotherVaultIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	sf, id := newTestVault(t, dir, "secrets.enc.yaml", map[string]string{"DB_PASSWORD": "hunter2"})
+	if err := sf.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(sf.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same file under another name, as after git mv or cp.
+	moved, err := ParseSecretFile(filepath.Join(dir, "secrets.prod.enc.yaml"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moved.Unlock(id); err != nil {
+		t.Fatal(err)
+	}
+	_, err = moved.GetSecret("DB_PASSWORD")
+	if err == nil {
+		t.Fatal("a value of secrets.enc.yaml decrypted in secrets.prod.enc.yaml")
+	}
+	if !strings.Contains(err.Error(), `"secrets.prod.enc.yaml"`) || !strings.Contains(err.Error(), "--renamed-from") {
+		t.Errorf("the error does not explain the file name binding: %v", err)
+	}
+
+	rebound, err := moved.Rebind("secrets.enc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebound) != 1 || rebound[0] != "DB_PASSWORD" {
+		t.Errorf("Rebind = %v, want [DB_PASSWORD]", rebound)
+	}
+	if v, err := moved.GetSecret("DB_PASSWORD"); err != nil || v != "hunter2" {
+		t.Fatalf("GetSecret after Rebind = %q, %v", v, err)
+	}
+
+	// Rebinding again finds nothing to do, and a wrong old name is an error
+	// rather than a silent no-op for values that do not decrypt.
+	if rebound, err := moved.Rebind("secrets.enc.yaml"); err != nil || len(rebound) != 0 {
+		t.Errorf("second Rebind = %v, %v", rebound, err)
+	}
+	secrets, _ := sf.ensureSecretsMap(false)
+	movedSecrets, _ := moved.ensureSecretsMap(false)
+	movedSecrets["DB_PASSWORD"] = secrets["DB_PASSWORD"]
+	if _, err := moved.Rebind("secrets.staging.enc.yaml"); err == nil {
+		t.Error("Rebind from a name the values were not written for succeeded")
+	}
+}
+
+func TestUnlockMatchesRecipientsByKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshIDs, err := crypto.ParseIdentityFile("", pem.EncodeToMemory(block), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As pasted from ~/.ssh/id_ed25519.pub, with its comment.
+	sshArg := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " alice@laptop"
+
+	x25519, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		identity age.Identity
+		arg      string
+		// edit changes the recorded arg after the DEK is wrapped.
+		edit func(string) string
+	}{
+		{"ssh key with a comment", sshIDs[0], sshArg, nil},
+		{"padded arg", x25519, x25519.Recipient().String(), func(arg string) string { return " " + arg + "\t" }},
+		{"arg of another key", x25519, x25519.Recipient().String(), func(string) string {
+			other, _ := age.GenerateX25519Identity()
+			return other.Recipient().String()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := NewSecretFile(filepath.Join(t.TempDir(), "secrets.enc.yaml"))
+			if err := sf.Init([]string{tt.arg}); err != nil {
+				t.Fatal(err)
+			}
+			if err := sf.SetSecret("A", "1"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.edit != nil {
+				meta, err := sf.metadataLocked()
+				if err != nil {
+					t.Fatal(err)
+				}
+				meta.Recipients[0].Arg = tt.edit(meta.Recipients[0].Arg)
+				sf.RawData[MetadataKey] = meta
+			}
+			sf.Lock()
+
+			if _, err := sf.Unlock(tt.identity); err != nil {
+				t.Fatalf("Unlock: %v", err)
+			}
+			if v, err := sf.GetSecret("A"); err != nil || v != "1" {
+				t.Errorf("GetSecret = %q, %v", v, err)
+			}
+		})
+	}
+}
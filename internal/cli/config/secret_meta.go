@@ -0,0 +1,265 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/flootic/envseal/internal/cli/crypto"
+)
+
+const secretMetaADLabel = "meta"
+
+var ErrInvalidInterval = errors.New("invalid interval (use e.g. 90d, 12w or 720h)")
+
+// SecretMeta describes a secret: why it exists, who owns it, when it last
+// changed and how often it must be rotated. Every field is optional. It is
+// kept under `secrets_meta:`, in plain YAML or, to hide it from people
+// without access, encrypted like the secret's value.
+type SecretMeta struct {
+	Description string    `yaml:"description,omitempty"`
+	Owner       string    `yaml:"owner,omitempty"`
+	CreatedAt   time.Time `yaml:"created_at,omitempty"`
+	CreatedBy   string    `yaml:"created_by,omitempty"`
+	UpdatedAt   time.Time `yaml:"updated_at,omitempty"`
+	UpdatedBy   string    `yaml:"updated_by,omitempty"`
+	// RotateEvery is the rotation policy, parsed by ParseInterval.
+	RotateEvery string `yaml:"rotate_every,omitempty"`
+	// ExpiresAt is when the secret stops being valid, in the format of
+	// User.ExpiresAt.
+	ExpiresAt string `yaml:"expires_at,omitempty"`
+}
+
+// Equal reports whether m and o hold the same metadata. Times are compared
+// as instants, so a value read back from YAML equals the one written.
+func (m SecretMeta) Equal(o SecretMeta) bool {
+	return m.Description == o.Description && m.Owner == o.Owner &&
+		m.CreatedAt.Equal(o.CreatedAt) && m.CreatedBy == o.CreatedBy &&
+		m.UpdatedAt.Equal(o.UpdatedAt) && m.UpdatedBy == o.UpdatedBy &&
+		m.RotateEvery == o.RotateEvery && m.ExpiresAt == o.ExpiresAt
+}
+
+// IsZero reports whether m holds no metadata.
+func (m SecretMeta) IsZero() bool {
+	return m.Equal(SecretMeta{})
+}
+
+// ParseInterval parses a rotation interval: a number of days ("90d") or
+// weeks ("12w"), or a Go duration ("720h").
+func ParseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, ErrInvalidInterval
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidInterval
+	}
+	return d, nil
+}
+
+// Validate checks the rotation interval and expiry of m.
+func (m SecretMeta) Validate() error {
+	if m.RotateEvery != "" {
+		if _, err := ParseInterval(m.RotateEvery); err != nil {
+			return fmt.Errorf("rotate_every %q: %w", m.RotateEvery, err)
+		}
+	}
+	if m.ExpiresAt != "" {
+		if _, err := ParseExpiry(m.ExpiresAt); err != nil {
+			return fmt.Errorf("expires_at %q: %w", m.ExpiresAt, err)
+		}
+	}
+	return nil
+}
+
+// RotationDue returns when the secret must next be rotated, and false if it
+// has no rotation policy or no recorded update.
+func (m SecretMeta) RotationDue() (time.Time, bool) {
+	interval, err := ParseInterval(m.RotateEvery)
+	if m.RotateEvery == "" || err != nil {
+		return time.Time{}, false
+	}
+	last := m.UpdatedAt
+	if last.IsZero() {
+		last = m.CreatedAt
+	}
+	if last.IsZero() {
+		return time.Time{}, false
+	}
+	return last.Add(interval), true
+}
+
+// RotationOverdue reports whether the rotation policy of the secret has
+// been exceeded at now.
+func (m SecretMeta) RotationOverdue(now time.Time) bool {
+	due, ok := m.RotationDue()
+	return ok && !now.Before(due)
+}
+
+// Expired reports whether the secret is past its expiry at now.
+func (m SecretMeta) Expired(now time.Time) bool {
+	t, err := ParseExpiry(m.ExpiresAt)
+	return m.ExpiresAt != "" && err == nil && !now.Before(t)
+}
+
+// SecretMeta returns the metadata of key, and false if it has none.
+// Encrypted metadata needs the DEK of the secret's group (ErrGroupLocked
+// otherwise).
+func (sf *SecretFile) SecretMeta(key string) (SecretMeta, bool, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.secretMetaLocked(key)
+}
+
+func (sf *SecretFile) secretMetaLocked(key string) (SecretMeta, bool, error) {
+	metas, err := sf.ensureTopLevelMap(SecretsMetaKey, false)
+	if err != nil || metas == nil {
+		return SecretMeta{}, false, err
+	}
+	raw, ok := metas[key]
+	if !ok || raw == nil {
+		return SecretMeta{}, false, nil
+	}
+
+	var data []byte
+	if enc, ok := raw.(string); ok {
+		if !isWrapped(enc, encPrefix) {
+			return SecretMeta{}, false, fmt.Errorf("invalid metadata of %s", key)
+		}
+		group, cipherText := splitEncrypted(enc)
+		dek := sf.deks[group]
+		if dek == nil {
+			return SecretMeta{}, false, ErrGroupLocked
+		}
+		plain, err := crypto.DecryptValue(cipherText, dek, sf.metaAD(key))
+		if err != nil {
+			return SecretMeta{}, false, fmt.Errorf("metadata of %s: %w", key, err)
+		}
+		data = []byte(plain)
+	} else if data, err = yaml.Marshal(raw); err != nil {
+		return SecretMeta{}, false, err
+	}
+
+	var m SecretMeta
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return SecretMeta{}, false, fmt.Errorf("invalid metadata of %s: %w", key, err)
+	}
+	return m, true, nil
+}
+
+// SetSecretMeta stores the metadata of an existing secret, encrypted with
+// the DEK of its group if encrypt is set, in plain YAML otherwise. Empty
+// metadata removes the entry.
+func (sf *SecretFile) SetSecretMeta(key string, m SecretMeta, encrypt bool) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	v, ok := sf.secretEntriesLocked()[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	metas, err := sf.ensureTopLevelMap(SecretsMetaKey, !m.IsZero())
+	if err != nil {
+		return err
+	}
+	if m.IsZero() {
+		delete(metas, key)
+		if len(metas) == 0 {
+			delete(sf.RawData, SecretsMetaKey)
+		}
+		return nil
+	}
+
+	if !encrypt {
+		metas[key] = m
+		return nil
+	}
+	return sf.encryptMetaLocked(metas, key, m, storedValueGroup(v))
+}
+
+func (sf *SecretFile) encryptMetaLocked(metas map[string]any, key string, m SecretMeta, group string) error {
+	dek := sf.deks[group]
+	if dek == nil {
+		return ErrGroupLocked
+	}
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	cipherText, err := crypto.EncryptValue(string(data), dek, sf.metaAD(key))
+	if err != nil {
+		return err
+	}
+	metas[key] = wrapEncrypted(group, cipherText)
+	return nil
+}
+
+// moveSecretMetaLocked re-encrypts the metadata of key for group, when it is
+// encrypted for another group.
+func (sf *SecretFile) moveSecretMetaLocked(key, group string) error {
+	metas, err := sf.ensureTopLevelMap(SecretsMetaKey, false)
+	if err != nil || metas == nil {
+		return err
+	}
+	enc, ok := metas[key].(string)
+	if !ok || storedValueGroup(enc) == group {
+		return nil
+	}
+	m, _, err := sf.secretMetaLocked(key)
+	if err != nil {
+		return err
+	}
+	return sf.encryptMetaLocked(metas, key, m, group)
+}
+
+// deleteSecretMetaLocked drops the metadata of key, if any.
+func (sf *SecretFile) deleteSecretMetaLocked(key string) {
+	if metas, err := sf.ensureTopLevelMap(SecretsMetaKey, false); err == nil && metas != nil {
+		delete(metas, key)
+		if len(metas) == 0 {
+			delete(sf.RawData, SecretsMetaKey)
+		}
+	}
+}
+
+// SecretKeys returns the names of every secret in the vault, sorted. It
+// does not need the file to be unlocked.
+func (sf *SecretFile) SecretKeys() []string {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+
+	entries := sf.secretEntriesLocked()
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metaAD binds encrypted metadata to its vault and key, and keeps it apart
+// from the value stored under the same key.
+func (sf *SecretFile) metaAD(key string) []byte {
+	return metaADFor(filepath.Base(sf.path), key)
+}
+
+func metaADFor(vaultName, key string) []byte {
+	return append(valueADFor(vaultName, key), "\x00"+secretMetaADLabel...)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecretMetaEqual(t *testing.T) {
+	now := time.Now()
+	m := SecretMeta{Owner: "payments", CreatedAt: now, UpdatedAt: now, RotateEvery: "90d"}
+
+	// What a save and load gives back: no monotonic reading, and another
+	// location.
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded SecretMeta
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	loaded.UpdatedAt = loaded.UpdatedAt.In(time.FixedZone("UTC+2", 2*3600))
+	if !m.Equal(loaded) || !loaded.Equal(m) {
+		t.Errorf("metadata read back differs: %+v, %+v", m, loaded)
+	}
+
+	changed := loaded
+	changed.UpdatedAt = changed.UpdatedAt.Add(time.Second)
+	if m.Equal(changed) {
+		t.Error("metadata with another update time is equal")
+	}
+	changed = loaded
+	changed.Owner = "billing"
+	if m.Equal(changed) {
+		t.Error("metadata with another owner is equal")
+	}
+
+	if m.IsZero() || !(SecretMeta{}).IsZero() {
+		t.Error("IsZero is wrong")
+	}
+	if (SecretMeta{CreatedAt: time.Unix(0, 0)}).IsZero() {
+		t.Error("metadata with a creation time is zero")
+	}
+}
+
+func TestSetSecretMetaZeroRemovesEntry(t *testing.T) {
+	sf, _ := newTestVault(t, t.TempDir(), "secrets.enc.yaml", map[string]string{"A": "1"})
+	if err := sf.SetSecretMeta("A", SecretMeta{Owner: "ops"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetSecretMeta("A", SecretMeta{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := sf.SecretMeta("A"); ok || err != nil {
+		t.Errorf("SecretMeta after clearing = %v, %v", ok, err)
+	}
+	if _, ok := sf.RawData[SecretsMetaKey]; ok {
+		t.Errorf("%s is left in the vault", SecretsMetaKey)
+	}
+}
@@ -0,0 +1,393 @@
+package config
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultYAMLIndent is the indentation of files written from scratch, the
+// one yaml.Marshal uses.
+const defaultYAMLIndent = 4
+
+// yamlDoc keeps the text and node tree a file was parsed from, so that saving
+// it only rewrites what changed: the lines of unchanged entries are copied
+// as they are, with their comments, quoting, spacing and blank lines, and
+// setting one value is a one-line diff.
+type yamlDoc struct {
+	root   *yaml.Node
+	data   []byte // the text root was parsed from
+	layout yamlLayout
+}
+
+// parseYAMLDoc parses data into a node tree and decodes it into v.
+func parseYAMLDoc(data []byte, v any) (*yamlDoc, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		// Empty file (or only comments): nothing to decode or keep.
+		return &yamlDoc{layout: defaultYAMLLayout()}, nil
+	}
+	if err := root.Decode(v); err != nil {
+		return nil, err
+	}
+	return &yamlDoc{root: &root, data: data, layout: detectYAMLLayout(&root, yamlLines(data))}, nil
+}
+
+// marshal encodes v, reusing the text of the parsed file wherever v still
+// holds the same data. A nil doc marshals v like yaml.Marshal.
+func (d *yamlDoc) marshal(v any) ([]byte, error) {
+	var updated yaml.Node
+	if err := updated.Encode(v); err != nil {
+		return nil, err
+	}
+
+	if d != nil && d.root != nil {
+		if out, root, ok := d.patch(&updated); ok {
+			d.root, d.data = root, out
+			return out, nil
+		}
+	}
+
+	// The file could not be patched (or there is none): encode the merged
+	// node tree, which still keeps order and comments.
+	layout := defaultYAMLLayout()
+	root := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	if d != nil {
+		if d.root != nil && d.root.Kind == yaml.DocumentNode && len(d.root.Content) == 1 {
+			doc := *d.root
+			doc.Content = []*yaml.Node{mergeYAMLNode(d.root.Content[0], &updated)}
+			root = &doc
+		}
+		layout = d.layout
+	}
+
+	out, err := encodeYAML(root, layout.indent)
+	if err != nil {
+		return nil, err
+	}
+	if d != nil {
+		if d.data != nil {
+			out = keepBlankLines(d.data, out, root)
+		}
+		// The next save patches what is now on disk.
+		var parsed yaml.Node
+		if err := yaml.Unmarshal(out, &parsed); err != nil {
+			return nil, err
+		}
+		d.root, d.data = &parsed, out
+	}
+	return out, nil
+}
+
+// encodeYAML encodes n with the given indentation.
+func encodeYAML(n *yaml.Node, indent int) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// keepBlankLines puts the blank lines of orig, which the encoder drops, back
+// into out around the lines both have in common. out is returned as is if
+// that would change its content.
+func keepBlankLines(orig, out []byte, root *yaml.Node) []byte {
+	oldLines, oldBlanks := splitBlankLines(orig)
+	newLines, newBlanks := splitBlankLines(out)
+
+	var buf bytes.Buffer
+	write := func(blanks []string, line string) {
+		for _, b := range blanks {
+			buf.WriteString(b + "\n")
+		}
+		buf.WriteString(line + "\n")
+	}
+
+	// pending holds the blank lines before removed lines, for the next line.
+	var pending []string
+	i, j := 0, 0
+	for j < len(newLines) {
+		if i < len(oldLines) && oldLines[i] == newLines[j] {
+			blanks := newBlanks[j]
+			if len(blanks) == 0 {
+				blanks = oldBlanks[i]
+			}
+			if len(blanks) == 0 {
+				blanks = pending
+			}
+			write(blanks, newLines[j])
+			pending = nil
+			i, j = i+1, j+1
+			continue
+		}
+
+		removed, added, ok := resyncLines(oldLines[i:], newLines[j:])
+		if !ok {
+			removed, added = len(oldLines)-i, len(newLines)-j
+		}
+		if removed > 0 && pending == nil {
+			pending = oldBlanks[i]
+		}
+		for k := range added {
+			blanks := newBlanks[j+k]
+			if k == 0 && removed > 0 && len(blanks) == 0 {
+				blanks, pending = pending, nil
+			}
+			write(blanks, newLines[j+k])
+		}
+		i, j = i+removed, j+added
+	}
+	trailing := newBlanks[len(newLines)]
+	if len(trailing) == 0 {
+		trailing = oldBlanks[len(oldLines)]
+	}
+	for _, b := range trailing {
+		buf.WriteString(b + "\n")
+	}
+
+	var check yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &check); err != nil || !equalYAMLNodes(&check, root) {
+		return out
+	}
+	return buf.Bytes()
+}
+
+// splitBlankLines returns the non-blank lines of data and, for each of them
+// and for the end of data, the blank lines that precede it.
+func splitBlankLines(data []byte) (lines []string, blanks [][]string) {
+	var run []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			run = append(run, line)
+			continue
+		}
+		lines = append(lines, line)
+		blanks = append(blanks, run)
+		run = nil
+	}
+	return lines, append(blanks, run)
+}
+
+// maxResyncDistance bounds the search for the next common line, so that a
+// rewritten file does not take quadratic time.
+const maxResyncDistance = 1000
+
+// resyncLines finds the closest place where orig and updated have lines in
+// common again, as the number of lines of each before it. A few lines must
+// match, so that a repeated line (created_by: ...) is not taken for it.
+func resyncLines(orig, updated []string) (removed, added int, ok bool) {
+	const run = 3
+	for d := 1; d <= maxResyncDistance && d <= len(orig)+len(updated); d++ {
+		for removed = 0; removed <= d; removed++ {
+			added = d - removed
+			n := 0
+			for removed+n < len(orig) && added+n < len(updated) && n < run &&
+				orig[removed+n] == updated[added+n] {
+				n++
+			}
+			if n == run || (n > 0 && (removed+n == len(orig) || added+n == len(updated))) {
+				return removed, added, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// mergeYAMLNode returns updated, with the nodes of orig kept wherever they
+// hold the same data. Mapping keys and sequence items keep their original
+// order; new ones are placed after the entry that precedes them in updated.
+func mergeYAMLNode(orig, updated *yaml.Node) *yaml.Node {
+	if orig == nil {
+		return updated
+	}
+	if equalYAMLNodes(orig, updated) {
+		return orig
+	}
+	if orig.Kind != updated.Kind || orig.Kind == yaml.AliasNode {
+		copyYAMLComments(orig, updated)
+		return updated
+	}
+
+	switch orig.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		merged := *orig
+		merged.Content = mergeYAMLEntries(orig, updated)
+		return &merged
+	default:
+		copyYAMLComments(orig, updated)
+		return updated
+	}
+}
+
+// yamlEntry is a key and its value in a mapping, or an item of a sequence.
+type yamlEntry struct {
+	id    string
+	nodes []*yaml.Node
+}
+
+func yamlEntries(n *yaml.Node) []yamlEntry {
+	var entries []yamlEntry
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			entries = append(entries, yamlEntry{id: n.Content[i].Value, nodes: n.Content[i : i+2]})
+		}
+		return entries
+	}
+	for _, item := range n.Content {
+		entries = append(entries, yamlEntry{id: yamlItemID(item), nodes: []*yaml.Node{item}})
+	}
+	return entries
+}
+
+// yamlItemID identifies a sequence item across versions of a file: a scalar
+// by its value, a mapping by its first field (a user's name, a recipient's
+// public key...).
+func yamlItemID(n *yaml.Node) string {
+	switch {
+	case n.Kind == yaml.ScalarNode:
+		return "=" + n.Value
+	case n.Kind == yaml.MappingNode && len(n.Content) >= 2 && n.Content[1].Kind == yaml.ScalarNode:
+		return n.Content[0].Value + "=" + n.Content[1].Value
+	}
+	return ""
+}
+
+// uniqueYAMLIDs indexes entries by id, or returns nil if some have none or
+// share one.
+func uniqueYAMLIDs(entries []yamlEntry) map[string]int {
+	ids := make(map[string]int, len(entries))
+	for i, e := range entries {
+		if _, dup := ids[e.id]; dup || e.id == "" {
+			return nil
+		}
+		ids[e.id] = i
+	}
+	return ids
+}
+
+func mergeYAMLEntries(orig, updated *yaml.Node) []*yaml.Node {
+	oldEntries, newEntries := yamlEntries(orig), yamlEntries(updated)
+	oldIDs, newIDs := uniqueYAMLIDs(oldEntries), uniqueYAMLIDs(newEntries)
+
+	if oldIDs == nil || newIDs == nil {
+		// Items cannot be told apart: pair them by position.
+		content := make([]*yaml.Node, 0, len(updated.Content))
+		for i, e := range newEntries {
+			for j, n := range e.nodes {
+				if i < len(oldEntries) && len(oldEntries[i].nodes) == len(e.nodes) {
+					n = mergeYAMLNode(oldEntries[i].nodes[j], n)
+				}
+				content = append(content, n)
+			}
+		}
+		return content
+	}
+
+	content := make([]*yaml.Node, 0, len(updated.Content))
+	for _, pair := range yamlEntryOrder(oldEntries, newEntries, oldIDs, newIDs) {
+		if pair.old < 0 {
+			content = append(content, newEntries[pair.new].nodes...)
+			continue
+		}
+		old, upd := oldEntries[pair.old], newEntries[pair.new]
+		for j := range old.nodes {
+			if j == 0 && orig.Kind == yaml.MappingNode {
+				content = append(content, old.nodes[j]) // the key, with its comments
+				continue
+			}
+			content = append(content, mergeYAMLNode(old.nodes[j], upd.nodes[j]))
+		}
+	}
+	return content
+}
+
+// yamlEntryPair is an entry of the saved file, with its index in the old
+// version of the collection (-1 for a new entry) and in the new one.
+type yamlEntryPair struct{ old, new int }
+
+// yamlEntryOrder returns the entries of the new version of a collection in
+// the order they are saved: entries still present keep their original order
+// and new ones are placed after the entry that precedes them in newEntries.
+// Both versions must have unique ids.
+func yamlEntryOrder(oldEntries, newEntries []yamlEntry, oldIDs, newIDs map[string]int) []yamlEntryPair {
+	var order []yamlEntryPair
+	for i, e := range oldEntries {
+		if j, ok := newIDs[e.id]; ok {
+			order = append(order, yamlEntryPair{old: i, new: j})
+		}
+	}
+	for i, e := range newEntries {
+		if _, ok := oldIDs[e.id]; ok {
+			continue
+		}
+		pos := 0
+		for p := i - 1; p >= 0; p-- {
+			if at := slices.IndexFunc(order, func(o yamlEntryPair) bool { return o.new == p }); at >= 0 {
+				pos = at + 1
+				break
+			}
+		}
+		order = slices.Insert(order, pos, yamlEntryPair{old: -1, new: i})
+	}
+	return order
+}
+
+// equalYAMLNodes reports whether a and b hold the same data, whatever their
+// style, comments and mapping key order.
+func equalYAMLNodes(a, b *yaml.Node) bool {
+	for a.Kind == yaml.AliasNode && a.Alias != nil {
+		a = a.Alias
+	}
+	for b.Kind == yaml.AliasNode && b.Alias != nil {
+		b = b.Alias
+	}
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+
+	switch a.Kind {
+	case yaml.ScalarNode:
+		return a.Value == b.Value && a.ShortTag() == b.ShortTag()
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			j := 0
+			for j < len(b.Content) && b.Content[j].Value != a.Content[i].Value {
+				j += 2
+			}
+			if j >= len(b.Content) || !equalYAMLNodes(a.Content[i+1], b.Content[j+1]) {
+				return false
+			}
+		}
+		return true
+	default:
+		for i := range a.Content {
+			if !equalYAMLNodes(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// copyYAMLComments moves the comments of a replaced node to its replacement.
+func copyYAMLComments(from, to *yaml.Node) {
+	if to.HeadComment == "" {
+		to.HeadComment = from.HeadComment
+	}
+	if to.LineComment == "" {
+		to.LineComment = from.LineComment
+	}
+	if to.FootComment == "" {
+		to.FootComment = from.FootComment
+	}
+}
@@ -0,0 +1,266 @@
+package config
+
+import "testing"
+
+// Hand-written manifests in the layouts people use. Saving them must keep
+// every line that holds the same data byte for byte.
+var handFormattedManifests = map[string]string{
+	"two spaces": `project_name: demo
+access_control:
+  - name: root
+    public_key: age1root
+    role: admin
+  - name: zed
+    public_key: age1zed
+groups:
+  - name: ops
+    members:
+      - root
+`,
+	"compact sequences": `project_name: demo
+access_control:
+- name: root
+  public_key: age1root
+  role: admin
+- name: zed
+  public_key: age1zed
+groups:
+- name: ops
+  members:
+  - root
+`,
+	"four spaces": `project_name: demo
+access_control:
+    - name: root
+      public_key: age1root
+      role: admin
+    - name: zed
+      public_key: age1zed
+groups:
+    - name: ops
+      members:
+          - root
+`,
+	"comments and blank lines": `# envseal manifest
+project_name: "demo"   # shown in prompts
+
+access_control:
+  - name: root   # me
+    public_key: age1root
+    role: admin
+
+  # contractor until the end of the year
+  - name: zed
+    public_key: age1zed
+
+# groups
+groups:
+  - name: ops
+    members: [root]
+`,
+}
+
+func TestManifestSaveUnchanged(t *testing.T) {
+	for name, in := range handFormattedManifests {
+		t.Run(name, func(t *testing.T) {
+			m, err := ParseManifest([]byte(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := m.doc.marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != in {
+				t.Errorf("saving an unchanged manifest rewrote it:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestManifestSaveKeepsLayout(t *testing.T) {
+	tests := []struct {
+		name, manifest string
+		edit           func(*Manifest) error
+		want           string
+	}{
+		{
+			name:     "add user, two spaces",
+			manifest: "two spaces",
+			edit:     func(m *Manifest) error { return m.AddUser("bob", "age1bob") },
+			want: `project_name: demo
+access_control:
+  - name: bob
+    public_key: age1bob
+  - name: root
+    public_key: age1root
+    role: admin
+  - name: zed
+    public_key: age1zed
+groups:
+  - name: ops
+    members:
+      - root
+`,
+		},
+		{
+			name:     "add user, compact sequences",
+			manifest: "compact sequences",
+			edit:     func(m *Manifest) error { return m.AddUser("sam", "age1sam") },
+			want: `project_name: demo
+access_control:
+- name: root
+  public_key: age1root
+  role: admin
+- name: sam
+  public_key: age1sam
+- name: zed
+  public_key: age1zed
+groups:
+- name: ops
+  members:
+  - root
+`,
+		},
+		{
+			name:     "add group member, four spaces",
+			manifest: "four spaces",
+			edit:     func(m *Manifest) error { return m.AddGroupMembers("ops", "zed") },
+			want: `project_name: demo
+access_control:
+    - name: root
+      public_key: age1root
+      role: admin
+    - name: zed
+      public_key: age1zed
+groups:
+    - name: ops
+      members:
+          - root
+          - zed
+`,
+		},
+		{
+			name:     "add user, comments and blank lines",
+			manifest: "comments and blank lines",
+			edit:     func(m *Manifest) error { return m.AddUser("sam", "age1sam") },
+			want: `# envseal manifest
+project_name: "demo"   # shown in prompts
+
+access_control:
+  - name: root   # me
+    public_key: age1root
+    role: admin
+
+  - name: sam
+    public_key: age1sam
+
+  # contractor until the end of the year
+  - name: zed
+    public_key: age1zed
+
+# groups
+groups:
+  - name: ops
+    members: [root]
+`,
+		},
+		{
+			name:     "add group member to a flow list",
+			manifest: "comments and blank lines",
+			edit:     func(m *Manifest) error { return m.AddGroupMembers("ops", "zed") },
+			want: `# envseal manifest
+project_name: "demo"   # shown in prompts
+
+access_control:
+  - name: root   # me
+    public_key: age1root
+    role: admin
+
+  # contractor until the end of the year
+  - name: zed
+    public_key: age1zed
+
+# groups
+groups:
+  - name: ops
+    members: [root, zed]
+`,
+		},
+		{
+			name:     "remove user, comments and blank lines",
+			manifest: "comments and blank lines",
+			edit: func(m *Manifest) error {
+				m.RemoveUser("zed")
+				return nil
+			},
+			want: `# envseal manifest
+project_name: "demo"   # shown in prompts
+
+access_control:
+  - name: root   # me
+    public_key: age1root
+    role: admin
+
+# groups
+groups:
+  - name: ops
+    members: [root]
+`,
+		},
+		{
+			name:     "change values, comments and blank lines",
+			manifest: "comments and blank lines",
+			edit: func(m *Manifest) error {
+				m.ProjectName = "demo2"
+				return m.SetRole("zed", RoleAdmin)
+			},
+			want: `# envseal manifest
+project_name: "demo2"   # shown in prompts
+
+access_control:
+  - name: root   # me
+    public_key: age1root
+    role: admin
+
+  # contractor until the end of the year
+  - name: zed
+    public_key: age1zed
+    role: admin
+
+# groups
+groups:
+  - name: ops
+    members: [root]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseManifest([]byte(handFormattedManifests[tt.manifest]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.edit(m); err != nil {
+				t.Fatal(err)
+			}
+			out, err := m.doc.marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out, tt.want)
+			}
+
+			// A second save starts from what the first one wrote.
+			again, err := m.doc.marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(out) {
+				t.Errorf("saving again changed the file:\n%s", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,568 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLayout is how a file indents its block collections, so that the
+// entries written into it look like the ones around them.
+type yamlLayout struct {
+	indent     int // of a mapping in a mapping
+	seqIndent  int // of the dash of a sequence in a mapping, from the key (0 for "key:\n- item")
+	itemIndent int // of the content of a sequence item, from its dash
+}
+
+func defaultYAMLLayout() yamlLayout {
+	return yamlLayout{indent: defaultYAMLIndent, seqIndent: defaultYAMLIndent, itemIndent: 2}
+}
+
+// detectYAMLLayout returns the layout of the first nested collections of
+// root, with the defaults of yaml.Marshal for what the file does not show.
+func detectYAMLLayout(root *yaml.Node, lines []string) yamlLayout {
+	indent, seqIndent, itemIndent := -1, -1, -1
+
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		case yaml.SequenceNode:
+			for _, item := range n.Content {
+				if itemIndent < 0 && isBlockYAMLCollection(item) && item.Kind == yaml.MappingNode {
+					if dash, ok := yamlDashColumn(lines, item); ok && dash < item.Column-1 {
+						itemIndent = item.Column - 1 - dash
+					}
+				}
+				walk(item)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if isBlockYAMLCollection(v) && v.Line > k.Line {
+					switch {
+					case v.Kind == yaml.MappingNode && indent < 0 && v.Content[0].Column > k.Column:
+						indent = v.Content[0].Column - k.Column
+					case v.Kind == yaml.SequenceNode && seqIndent < 0:
+						if dash, ok := yamlDashColumn(lines, v.Content[0]); ok && dash >= k.Column-1 {
+							seqIndent = dash - (k.Column - 1)
+						}
+					}
+				}
+				walk(v)
+			}
+		}
+	}
+	walk(root)
+
+	// A file without nested mappings (a manifest made of lists) is still
+	// indented by the step its lists use.
+	if indent < 0 {
+		switch {
+		case seqIndent > 0:
+			indent = seqIndent
+		case itemIndent > 0:
+			indent = itemIndent
+		default:
+			indent = defaultYAMLIndent
+		}
+	}
+	if seqIndent < 0 {
+		seqIndent = indent
+	}
+	if itemIndent < 0 {
+		itemIndent = 2
+	}
+	return yamlLayout{indent: indent, seqIndent: seqIndent, itemIndent: itemIndent}
+}
+
+// yamlLines splits data into lines, without the final newline.
+func yamlLines(data []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// patch writes updated by copying the lines of the parsed file and only
+// rewriting the entries that changed. It returns the new text and its node
+// tree, or false when the file is laid out in a way it does not handle (flow
+// collections at the top, CRLF line endings...) or the result would not
+// decode to updated.
+func (d *yamlDoc) patch(updated *yaml.Node) ([]byte, *yaml.Node, bool) {
+	if d.root.Kind != yaml.DocumentNode || len(d.root.Content) != 1 || bytes.ContainsRune(d.data, '\r') {
+		return nil, nil, false
+	}
+	orig := d.root.Content[0]
+	if !isBlockYAMLCollection(orig) || orig.Kind != yaml.MappingNode || updated.Kind != yaml.MappingNode {
+		return nil, nil, false
+	}
+
+	p := &yamlPatcher{lines: yamlLines(d.data), layout: d.layout}
+	body := p.lines
+	if !equalYAMLNodes(orig, updated) {
+		var ok bool
+		if body, ok = p.collection(orig, updated, 0, len(p.lines)); !ok {
+			return nil, nil, false
+		}
+	}
+	out := []byte(strings.Join(body, "\n") + "\n")
+
+	// The saved file keeps the original order of entries, which is what the
+	// merged tree holds.
+	want := mergeYAMLNode(orig, updated)
+	var check yaml.Node
+	if err := yaml.Unmarshal(out, &check); err != nil ||
+		check.Kind != yaml.DocumentNode || len(check.Content) != 1 || !equalYAMLNodes(check.Content[0], want) {
+		return nil, nil, false
+	}
+	return out, &check, true
+}
+
+// yamlPatcher rewrites the changed parts of a file, given as lines.
+type yamlPatcher struct {
+	lines  []string
+	layout yamlLayout
+}
+
+// yamlSpan is where an entry of a block collection is in the file: its
+// leading comment and blank lines are lines[prefix:start], the entry itself
+// lines[start:end]. col is the column of its key or dash.
+type yamlSpan struct {
+	prefix, start, end int
+	col                int
+}
+
+// spans locates the entries of the block collection n, whose entries are in
+// lines[start:end].
+func (p *yamlPatcher) spans(n *yaml.Node, entries []yamlEntry, start, end int) ([]yamlSpan, bool) {
+	spans := make([]yamlSpan, len(entries))
+	for i, e := range entries {
+		first := e.nodes[0]
+		line, col := first.Line-1, first.Column-1
+		if n.Kind == yaml.MappingNode {
+			if first.Kind != yaml.ScalarNode {
+				return nil, false
+			}
+		} else {
+			var ok bool
+			if col, ok = yamlDashColumn(p.lines, first); !ok {
+				return nil, false
+			}
+		}
+
+		lower := start
+		if i > 0 {
+			lower = spans[i-1].start + 1
+		}
+		if line < lower || line >= end || (i > 0 && col != spans[0].col) {
+			return nil, false
+		}
+		prefix := line
+		for prefix > lower && isYAMLFiller(p.lines[prefix-1]) {
+			prefix--
+		}
+		spans[i] = yamlSpan{prefix: prefix, start: line, col: col}
+		if i > 0 {
+			spans[i-1].end = prefix
+		}
+	}
+
+	last := &spans[len(spans)-1]
+	last.end = end
+	for last.end > last.start+1 && isYAMLFiller(p.lines[last.end-1]) {
+		last.end--
+	}
+	return spans, true
+}
+
+// collection returns lines[start:end], which hold the block collection
+// orig, rewritten to hold updated: unchanged entries are copied, changed ones
+// patched or written anew, removed ones dropped and new ones inserted.
+func (p *yamlPatcher) collection(orig, updated *yaml.Node, start, end int) ([]string, bool) {
+	if !isBlockYAMLCollection(orig) || orig.Kind != updated.Kind || len(updated.Content) == 0 {
+		return nil, false
+	}
+	oldEntries, newEntries := yamlEntries(orig), yamlEntries(updated)
+	oldIDs, newIDs := uniqueYAMLIDs(oldEntries), uniqueYAMLIDs(newEntries)
+	if oldIDs == nil || newIDs == nil {
+		return nil, false
+	}
+	spans, ok := p.spans(orig, oldEntries, start, end)
+	if !ok {
+		return nil, false
+	}
+
+	col := spans[0].col
+	// The first entry shares its line with what precedes the collection,
+	// such as the dash of the sequence item it is in.
+	lead := runePrefix(p.lines[spans[0].start], col)
+	pad := strings.Repeat(" ", col)
+	// Block scalars are indented from the dash of the item a mapping is in,
+	// like yaml.v3 writes them.
+	base := col
+	if dash := strings.LastIndex(lead, "-"); dash >= 0 {
+		base = utf8.RuneCountInString(lead[:dash])
+	}
+	firstBlanks := leadingBlankLines(p.lines[spans[0].prefix:spans[0].start])
+	sepBlanks := 0
+	if len(spans) > 1 {
+		sepBlanks = leadingBlankLines(p.lines[spans[1].prefix:spans[1].start])
+	}
+
+	out := slices.Clone(p.lines[start:spans[0].prefix])
+	for n, pair := range yamlEntryOrder(oldEntries, newEntries, oldIDs, newIDs) {
+		var prefix, body []string
+		if pair.old >= 0 {
+			s := spans[pair.old]
+			prefix = p.lines[s.prefix:s.start]
+			if body, ok = p.entry(orig.Kind, oldEntries[pair.old], newEntries[pair.new], s); !ok {
+				body, ok = p.render(orig.Kind, mergedYAMLEntry(orig.Kind, oldEntries[pair.old], newEntries[pair.new]), col, base)
+			}
+		} else {
+			prefix = blankLines(sepBlanks)
+			body, ok = p.render(orig.Kind, newEntries[pair.new].nodes, col, base)
+		}
+		if !ok {
+			return nil, false
+		}
+
+		if n == 0 && pair.old != 0 {
+			prefix = append(blankLines(firstBlanks), trimLeadingBlankLines(prefix)...)
+		}
+		linePrefix := pad
+		if n == 0 {
+			linePrefix = lead
+		}
+		if got := runePrefix(body[0], col); got != lead && got != pad {
+			return nil, false
+		}
+		body[0] = linePrefix + runeSuffix(body[0], col)
+
+		out = append(out, prefix...)
+		out = append(out, body...)
+	}
+	return append(out, p.lines[spans[len(spans)-1].end:end]...), true
+}
+
+// entry patches the lines of an entry whose value changed, or returns false
+// when it has to be written anew.
+func (p *yamlPatcher) entry(kind yaml.Kind, old, upd yamlEntry, s yamlSpan) ([]string, bool) {
+	lines := p.lines[s.start:s.end]
+	if kind == yaml.MappingNode {
+		k, ov, uv := old.nodes[0], old.nodes[1], upd.nodes[1]
+		switch {
+		case equalYAMLNodes(ov, uv):
+			return slices.Clone(lines), true
+		case isBlockYAMLCollection(ov) && ov.Kind == uv.Kind && ov.Line > k.Line:
+			body, ok := p.collection(ov, uv, s.start+1, s.end)
+			return append([]string{lines[0]}, body...), ok
+		case ov.Kind == yaml.ScalarNode && uv.Kind == yaml.ScalarNode && ov.Line == k.Line && len(lines) == 1:
+			line, ok := replaceYAMLScalar(lines[0], ov, uv)
+			return []string{line}, ok
+		}
+		return nil, false
+	}
+
+	o, u := old.nodes[0], upd.nodes[0]
+	switch {
+	case equalYAMLNodes(o, u):
+		return slices.Clone(lines), true
+	case isBlockYAMLCollection(o) && o.Kind == yaml.MappingNode && u.Kind == yaml.MappingNode && o.Line-1 == s.start:
+		return p.collection(o, u, s.start, s.end)
+	case o.Kind == yaml.ScalarNode && u.Kind == yaml.ScalarNode && len(lines) == 1:
+		line, ok := replaceYAMLScalar(lines[0], o, u)
+		return []string{line}, ok
+	}
+	return nil, false
+}
+
+// mergedYAMLEntry returns the nodes to write for an entry that changed:
+// the original key and the merged value, which keep their comments.
+func mergedYAMLEntry(kind yaml.Kind, old, upd yamlEntry) []*yaml.Node {
+	if kind == yaml.MappingNode {
+		return []*yaml.Node{old.nodes[0], mergeYAMLNode(old.nodes[1], upd.nodes[1])}
+	}
+	return []*yaml.Node{mergeYAMLNode(old.nodes[0], upd.nodes[0])}
+}
+
+// replaceYAMLScalar replaces the scalar orig in line by updated, keeping
+// what follows it (the spacing before a comment, the comment). The quoting
+// of orig is kept when updated can use it.
+func replaceYAMLScalar(line string, orig, updated *yaml.Node) (string, bool) {
+	if orig.Anchor != "" || orig.Style&(yaml.TaggedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return "", false
+	}
+	before, rest := runePrefix(line, orig.Column-1), runeSuffix(line, orig.Column-1)
+	n, ok := yamlScalarLength(rest, orig.Style)
+	if !ok {
+		return "", false
+	}
+
+	text, ok := "", false
+	if orig.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		text, ok = yamlScalarText(updated, orig.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle))
+	}
+	if !ok {
+		if text, ok = yamlScalarText(updated, updated.Style); !ok {
+			return "", false
+		}
+	}
+	return before + text + rest[n:], true
+}
+
+// yamlScalarLength returns the length in bytes of the single-line scalar at
+// the start of s.
+func yamlScalarLength(s string, style yaml.Style) (int, bool) {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, true
+			}
+		}
+		return 0, false
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, true
+		}
+		return 0, false
+	default:
+		end := len(s)
+		for i := 1; i < len(s); i++ {
+			if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+				end = i
+				break
+			}
+		}
+		return len(strings.TrimRight(s[:end], " \t")), true
+	}
+}
+
+// yamlScalarText returns n written on one line in the given style, or false
+// if it does not fit on one line.
+func yamlScalarText(n *yaml.Node, style yaml.Style) (string, bool) {
+	c := *n
+	c.Style = style
+	c.HeadComment, c.LineComment, c.FootComment = "", "", ""
+	out, err := yaml.Marshal(&c)
+	if err != nil {
+		return "", false
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(text, "\n") {
+		return "", false
+	}
+	// The encoder quotes what would not read back as the same value, in
+	// which case the requested style may not have been used.
+	var back yaml.Node
+	if yaml.Unmarshal(out, &back) != nil || len(back.Content) != 1 || !equalYAMLNodes(back.Content[0], n) {
+		return "", false
+	}
+	return text, true
+}
+
+// render writes an entry of a collection of the given kind at column col:
+// a key and its value for a mapping, an item for a sequence. Its head
+// comment is left out, it is kept with the lines before the entry. Block
+// scalars are indented from column base.
+func (p *yamlPatcher) render(kind yaml.Kind, nodes []*yaml.Node, col, base int) ([]string, bool) {
+	if kind == yaml.MappingNode {
+		return p.renderPair(nodes[0], nodes[1], col, base, false)
+	}
+	return p.renderItem(nodes[0], col, false)
+}
+
+func (p *yamlPatcher) renderPair(k, v *yaml.Node, col, base int, withHead bool) ([]string, bool) {
+	pad := strings.Repeat(" ", col)
+	var out []string
+	if withHead {
+		out = yamlCommentLines(k.HeadComment, pad)
+	}
+
+	if !isBlockYAMLCollection(v) {
+		// A scalar, alias or flow collection: the encoder writes it, on one
+		// line or as a block scalar indented from the key.
+		pair := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{withoutYAMLComments(k, true), withoutYAMLComments(v, false)}}
+		data, err := encodeYAML(pair, p.layout.indent)
+		if err != nil {
+			return nil, false
+		}
+		for i, line := range yamlLines(data) {
+			switch {
+			case i == 0:
+				line = pad + line
+			case line != "":
+				line = strings.Repeat(" ", base) + line
+			}
+			out = append(out, line)
+		}
+		return out, true
+	}
+
+	key, ok := yamlScalarText(k, k.Style)
+	if !ok {
+		return nil, false
+	}
+	line := pad + key + ":"
+	if c := cmp.Or(k.LineComment, v.LineComment); c != "" {
+		line += " " + c
+	}
+	out = append(out, line)
+
+	if v.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			lines, ok := p.renderPair(v.Content[i], v.Content[i+1], col+p.layout.indent, col+p.layout.indent, true)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, lines...)
+		}
+		return out, true
+	}
+	for _, item := range v.Content {
+		lines, ok := p.renderItem(item, col+p.layout.seqIndent, true)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, lines...)
+	}
+	return out, true
+}
+
+func (p *yamlPatcher) renderItem(v *yaml.Node, col int, withHead bool) ([]string, bool) {
+	pad := strings.Repeat(" ", col)
+	var out []string
+	if withHead {
+		out = yamlCommentLines(v.HeadComment, pad)
+	}
+
+	if v.Kind != yaml.MappingNode || !isBlockYAMLCollection(v) {
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{withoutYAMLComments(v, true)}}
+		data, err := encodeYAML(seq, p.layout.indent)
+		if err != nil {
+			return nil, false
+		}
+		for _, line := range yamlLines(data) {
+			if line != "" {
+				line = pad + line
+			}
+			out = append(out, line)
+		}
+		return out, true
+	}
+
+	// The first field goes on the line of the dash, its comments above it.
+	inner := col + p.layout.itemIndent
+	out = append(out, yamlCommentLines(v.Content[0].HeadComment, pad)...)
+	first := len(out)
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		lines, ok := p.renderPair(v.Content[i], v.Content[i+1], inner, col, i > 0)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, lines...)
+	}
+	out[first] = pad + "-" + strings.Repeat(" ", p.layout.itemIndent-1) + out[first][inner:]
+	return out, true
+}
+
+// withoutYAMLComments returns a copy of n without its foot comment, nor its
+// head comment when head is set, which the caller writes or keeps itself.
+func withoutYAMLComments(n *yaml.Node, head bool) *yaml.Node {
+	c := *n
+	c.FootComment = ""
+	if head {
+		c.HeadComment = ""
+	}
+	return &c
+}
+
+func yamlCommentLines(comment, pad string) []string {
+	if comment == "" {
+		return nil
+	}
+	var out []string
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" {
+			line = pad + line
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// isBlockYAMLCollection reports whether n is a non-empty mapping or sequence
+// written one entry per line.
+func isBlockYAMLCollection(n *yaml.Node) bool {
+	return (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) &&
+		n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// yamlDashColumn returns the column of the dash of the sequence item n.
+func yamlDashColumn(lines []string, n *yaml.Node) (int, bool) {
+	if n.Line < 1 || n.Line > len(lines) {
+		return 0, false
+	}
+	before := strings.TrimRight(runePrefix(lines[n.Line-1], n.Column-1), " ")
+	if !strings.HasSuffix(before, "-") {
+		return 0, false
+	}
+	return utf8.RuneCountInString(before) - 1, true
+}
+
+// isYAMLFiller reports whether line is blank or only a comment.
+func isYAMLFiller(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "#")
+}
+
+func leadingBlankLines(lines []string) int {
+	n := 0
+	for n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+		n++
+	}
+	return n
+}
+
+func trimLeadingBlankLines(lines []string) []string {
+	return lines[leadingBlankLines(lines):]
+}
+
+func blankLines(n int) []string {
+	return make([]string, n)
+}
+
+// runePrefix returns the first col characters of s (YAML columns count
+// characters, not bytes).
+func runePrefix(s string, col int) string {
+	return s[:runeOffset(s, col)]
+}
+
+func runeSuffix(s string, col int) string {
+	return s[runeOffset(s, col):]
+}
+
+func runeOffset(s string, col int) int {
+	for i := range s {
+		if col == 0 {
+			return i
+		}
+		col--
+	}
+	return len(s)
+}
@@ -7,14 +7,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/plugin"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
 const (
 	dekSize = chacha20poly1305.KeySize // 32 bytes
 
+	hybridRecipientPrefix = "age1pq1"
+	hybridIdentityPrefix  = "AGE-SECRET-KEY-PQ-1"
+
 	errDecryptDEKDenied = "failed to decrypt DEK (denied or invalid identity)"
 	errCiphertextShort  = "ciphertext too short"
 	errDecryptValue     = "failed to decrypt value (possible corruption or incorrect DEK)"
@@ -36,19 +42,40 @@ func EncryptDEK(dek []byte, recipientPubKeys []string) (string, error) {
 		return "", err
 	}
 
+	return EncryptKeyMaterial(dek, recipientPubKeys)
+}
+
+// EncryptKeyMaterial encrypts arbitrary key material (e.g. a recovery share)
+// for a list of recipients, in the same envelope format as EncryptDEK.
+func EncryptKeyMaterial(data []byte, recipientPubKeys []string) (string, error) {
 	recipients, err := parseRecipients(recipientPubKeys)
 	if err != nil {
 		return "", err
 	}
 
 	var out bytes.Buffer
-	if err := encryptToAge(&out, dek, recipients); err != nil {
+	if err := encryptToAge(&out, data, recipients); err != nil {
 		return "", err
 	}
 
 	return out.String(), nil
 }
 
+// DecryptKeyMaterial decrypts an envelope produced by EncryptKeyMaterial with
+// any of the given identities.
+func DecryptKeyMaterial(enc string, identities ...age.Identity) ([]byte, error) {
+	if enc == "" || len(identities) == 0 {
+		return nil, errors.New(errDecryptDEKDenied)
+	}
+
+	r, err := age.Decrypt(bytes.NewBufferString(enc), identities...)
+	if err != nil {
+		return nil, errors.New(errDecryptDEKDenied)
+	}
+
+	return io.ReadAll(r)
+}
+
 // DecryptDEK decrypts the "envelope" to obtain the raw DEK using the user's identity.
 func DecryptDEK(encryptedDEK string, identity age.Identity) ([]byte, error) {
 	if encryptedDEK == "" {
@@ -81,8 +108,10 @@ func DecryptDEK(encryptedDEK string, identity age.Identity) ([]byte, error) {
 }
 
 // EncryptValue encrypts a string using ChaCha20-Poly1305 with the DEK.
+// additionalData is authenticated but not stored: the exact same bytes must be
+// passed to DecryptValue. A nil value produces the legacy (unbound) format.
 // Output format: Base64(Nonce + Ciphertext)
-func EncryptValue(plaintext string, dek []byte) (string, error) {
+func EncryptValue(plaintext string, dek []byte, additionalData []byte) (string, error) {
 	if err := validateDEK(dek); err != nil {
 		return "", err
 	}
@@ -100,13 +129,14 @@ func EncryptValue(plaintext string, dek []byte) (string, error) {
 	// Build output as Nonce || Ciphertext in a single slice (no aliasing tricks).
 	out := make([]byte, 0, len(nonce)+len(plaintext)+aead.Overhead())
 	out = append(out, nonce...)
-	out = aead.Seal(out, nonce, []byte(plaintext), nil)
+	out = aead.Seal(out, nonce, []byte(plaintext), additionalData)
 
 	return base64.StdEncoding.EncodeToString(out), nil
 }
 
-// DecryptValue decrypts a Base64 string using the DEK.
-func DecryptValue(encryptedBase64 string, dek []byte) (string, error) {
+// DecryptValue decrypts a Base64 string using the DEK and the additional data
+// that was supplied to EncryptValue.
+func DecryptValue(encryptedBase64 string, dek []byte, additionalData []byte) (string, error) {
 	if err := validateDEK(dek); err != nil {
 		return "", err
 	}
@@ -129,7 +159,7 @@ func DecryptValue(encryptedBase64 string, dek []byte) (string, error) {
 	nonce := data[:nonceSize]
 	ciphertext := data[nonceSize:]
 
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return "", errors.New(errDecryptValue)
 	}
@@ -144,7 +174,7 @@ func parseRecipients(pubKeys []string) ([]age.Recipient, error) {
 
 	recipients := make([]age.Recipient, 0, len(pubKeys))
 	for _, pubKey := range pubKeys {
-		r, err := age.ParseX25519Recipient(pubKey)
+		r, err := ParseRecipient(pubKey)
 		if err != nil {
 			return nil, fmt.Errorf("invalid public key: %w", err)
 		}
@@ -153,6 +183,45 @@ func parseRecipients(pubKeys []string) ([]age.Recipient, error) {
 	return recipients, nil
 }
 
+// ParseRecipient parses a public key as written in the manifest: an age
+// X25519 recipient (age1...), a post-quantum hybrid recipient (age1pq1...),
+// an age plugin recipient (age1<plugin>1...) or an SSH public key
+// (ssh-ed25519 / ssh-rsa).
+func ParseRecipient(pubKey string) (age.Recipient, error) {
+	pubKey = strings.TrimSpace(pubKey)
+	switch {
+	case IsSSHRecipient(pubKey):
+		return agessh.ParseRecipient(pubKey)
+	case IsPostQuantumRecipient(pubKey):
+		return age.ParseHybridRecipient(pubKey)
+	case IsPluginRecipient(pubKey):
+		return plugin.NewRecipient(pubKey, pluginUI)
+	default:
+		return age.ParseX25519Recipient(pubKey)
+	}
+}
+
+// IsPostQuantumRecipient reports whether pubKey is a hybrid ML-KEM-768 + X25519
+// recipient, which protects wrapped keys against future quantum computers.
+func IsPostQuantumRecipient(pubKey string) bool {
+	return strings.HasPrefix(strings.TrimSpace(pubKey), hybridRecipientPrefix)
+}
+
+// NormalizeRecipient validates a public key and returns its canonical form,
+// which is what gets stored in the manifest and compared against identities.
+// SSH keys lose their trailing comment.
+func NormalizeRecipient(pubKey string) (string, error) {
+	pubKey = strings.TrimSpace(pubKey)
+	if IsSSHRecipient(pubKey) {
+		recipient, _, err := ParseSSHPublicKey(pubKey)
+		return recipient, err
+	}
+	if _, err := ParseRecipient(pubKey); err != nil {
+		return "", err
+	}
+	return pubKey, nil
+}
+
 func encryptToAge(w io.Writer, dek []byte, recipients []age.Recipient) error {
 	aw, err := age.Encrypt(w, recipients...)
 	if err != nil {
@@ -0,0 +1,70 @@
+package crypto
+
+import "strings"
+
+// Minimal Bech32 (BIP 173) encoder, used to build age identity strings from
+// raw key material. age keeps its own implementation internal.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range 5 {
+			if top>>i&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32Encode encodes data under hrp. The result is upper case if hrp is.
+func bech32Encode(hrp string, data []byte) string {
+	lowerHRP := strings.ToLower(hrp)
+
+	// Regroup 8-bit bytes into 5-bit values, padding the last one.
+	var values []byte
+	acc, bits := uint32(0), 0
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits))&31)
+	}
+
+	expanded := make([]byte, 0, 2*len(lowerHRP)+1+len(values)+6)
+	for _, c := range []byte(lowerHRP) {
+		expanded = append(expanded, c>>5)
+	}
+	expanded = append(expanded, 0)
+	for _, c := range []byte(lowerHRP) {
+		expanded = append(expanded, c&31)
+	}
+	expanded = append(expanded, values...)
+	mod := bech32Polymod(append(expanded, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var sb strings.Builder
+	sb.WriteString(lowerHRP)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := range 6 {
+		sb.WriteByte(bech32Charset[mod>>(5*(5-i))&31])
+	}
+
+	if hrp == lowerHRP {
+		return sb.String()
+	}
+	return strings.ToUpper(sb.String())
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultGenerateSpec is the value generated when no spec is given: 32
+// letters and digits.
+const DefaultGenerateSpec = "alnum:32"
+
+const (
+	alnumAlphabet   = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	maxGenerateSize = 4096
+)
+
+// GenerateSecret returns a random value described by spec, "KIND[:N]":
+//
+//	alnum:N   N letters and digits (default 32)
+//	hex:N     N random bytes, hex-encoded (default 32)
+//	base64:N  N random bytes, base64-encoded (default 32)
+//	uuid      a random (version 4) UUID
+func GenerateSecret(spec string) (string, error) {
+	kind, sizeStr, hasSize := strings.Cut(strings.TrimSpace(spec), ":")
+	size := 32
+	if hasSize {
+		n, err := strconv.Atoi(sizeStr)
+		if err != nil || n <= 0 || n > maxGenerateSize {
+			return "", fmt.Errorf("invalid size %q in %q (1-%d)", sizeStr, spec, maxGenerateSize)
+		}
+		size = n
+	}
+
+	switch kind {
+	case "alnum":
+		return randomAlnum(size)
+	case "hex":
+		b, err := randomBytes(size)
+		return hex.EncodeToString(b), err
+	case "base64":
+		b, err := randomBytes(size)
+		return base64.StdEncoding.EncodeToString(b), err
+	case "uuid":
+		if hasSize {
+			return "", errors.New("uuid takes no size")
+		}
+		b, err := randomBytes(16)
+		if err != nil {
+			return "", err
+		}
+		b[6] = b[6]&0x0f | 0x40 // version 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	default:
+		return "", fmt.Errorf("unknown generator %q (use alnum, hex, base64 or uuid)", kind)
+	}
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return b, nil
+}
+
+// randomAlnum draws n characters uniformly from alnumAlphabet, rejecting
+// the bytes that would bias the modulo.
+func randomAlnum(n int) (string, error) {
+	const limit = 256 - 256%len(alnumAlphabet)
+
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for _, c := range buf {
+			if int(c) < limit && len(out) < n {
+				out = append(out, alnumAlphabet[int(c)%len(alnumAlphabet)])
+			}
+		}
+	}
+	return string(out), nil
+}
@@ -1,12 +1,17 @@
 package crypto
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 
 	"filippo.io/age"
 )
 
+// PassphraseFunc returns the passphrase protecting a key, typically by prompting the user.
+type PassphraseFunc func() ([]byte, error)
+
 // GenerateIdentity generates a new X25519 identity and its corresponding recipient.
 func GenerateIdentity() (string, string, error) {
 	identity, err := age.GenerateX25519Identity()
@@ -17,19 +22,121 @@ func GenerateIdentity() (string, string, error) {
 	return identity.String(), identity.Recipient().String(), nil
 }
 
-// GetIdentityFromKeyFile reads an identity from a given file path.
-func GetIdentityFromKeyFile(path string) (*age.X25519Identity, error) {
-	content, err := os.ReadFile(path)
+// GenerateHybridIdentity generates a new post-quantum hybrid (ML-KEM-768 + X25519)
+// identity and its corresponding recipient.
+func GenerateHybridIdentity() (string, string, error) {
+	identity, err := age.GenerateHybridIdentity()
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	keyStr := strings.TrimSpace(string(content))
+	return identity.String(), identity.Recipient().String(), nil
+}
 
-	identity, err := age.ParseX25519Identity(keyStr)
+// GetIdentitiesFromKeyFile reads the identities stored in a file.
+// The file holds age secret keys (AGE-SECRET-KEY-1... or the hybrid
+// AGE-SECRET-KEY-PQ-1...) and age plugin identities (AGE-PLUGIN-...), one per
+// line and optionally passphrase-protected as a whole by EncryptIdentity, or
+// a single SSH private key (ed25519 or RSA). passphrase is called right away
+// for encrypted age identities, and only on first use for encrypted SSH keys.
+func GetIdentitiesFromKeyFile(path string, passphrase PassphraseFunc) ([]age.Identity, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return identity, nil
+	return ParseIdentityFile(path, content, passphrase)
+}
+
+// ParseIdentityFile parses identity file content that did not necessarily
+// come from disk (environment variable, file descriptor, command output).
+// path is only used to locate the ".pub" file of encrypted PEM SSH keys and
+// may be empty.
+func ParseIdentityFile(path string, content []byte, passphrase PassphraseFunc) ([]age.Identity, error) {
+	if IsEncryptedIdentity(content) {
+		keys, err := decryptIdentity(content, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return ParseIdentities(keys)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "-----BEGIN") {
+		id, err := parseSSHIdentity(path, content, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{id}, nil
+	}
+
+	return ParseIdentities(string(content))
+}
+
+// ParseIdentities parses unencrypted age identities, one per line. Blank
+// lines and "#" comments are ignored, except that a "# public key: age1..."
+// (or "# Recipient: ...") comment names the recipient of the plugin identity
+// that follows it, as written by age-keygen and plugins like age-plugin-yubikey.
+func ParseIdentities(text string) ([]age.Identity, error) {
+	var identities []age.Identity
+	var publicKey string
+
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			label, value, found := strings.Cut(comment, ":")
+			switch strings.ToLower(strings.TrimSpace(label)) {
+			case "recipient", "public key":
+				if found {
+					publicKey = strings.TrimSpace(value)
+				}
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		id, err := parseIdentityLine(line, publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		identities = append(identities, id)
+		publicKey = ""
+	}
+
+	if len(identities) == 0 {
+		return nil, errors.New("no identity found")
+	}
+	return identities, nil
+}
+
+func parseIdentityLine(line, publicKey string) (age.Identity, error) {
+	switch {
+	case strings.HasPrefix(line, pluginIdentityPrefix):
+		return parsePluginIdentity(line, publicKey)
+	case strings.HasPrefix(line, hybridIdentityPrefix):
+		return age.ParseHybridIdentity(line)
+	default:
+		return age.ParseX25519Identity(line)
+	}
+}
+
+// IdentityRecipient returns the public key matching identity, in the same
+// form as it is written in the manifest.
+func IdentityRecipient(identity age.Identity) (string, error) {
+	switch id := identity.(type) {
+	case *age.X25519Identity:
+		return id.Recipient().String(), nil
+	case *age.HybridIdentity:
+		return id.Recipient().String(), nil
+	case *sshIdentity:
+		return id.publicKey, nil
+	case *pluginIdentity:
+		if id.publicKey == "" {
+			return "", fmt.Errorf("plugin identity has no '# public key: age1...' comment; add the recipient printed by age-plugin-%s", id.Name())
+		}
+		return id.publicKey, nil
+	default:
+		return "", errors.New("cannot derive a public key from this identity")
+	}
 }
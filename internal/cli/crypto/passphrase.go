@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+const errDecryptIdentity = "failed to decrypt identity (wrong passphrase?)"
+
+// EncryptIdentity protects an age secret key with a passphrase (age scrypt
+// recipient) and returns the ASCII-armored content of the identity file.
+func EncryptIdentity(privKey string, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	r, err := age.NewScryptRecipient(string(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	aw := armor.NewWriter(&out)
+	if err := encryptToAge(aw, []byte(strings.TrimSpace(privKey)+"\n"), []age.Recipient{r}); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// IsEncryptedIdentity reports whether content is a passphrase-protected
+// identity file written by EncryptIdentity.
+func IsEncryptedIdentity(content []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(content)), armor.Header)
+}
+
+// IsEncryptedKeyFile reports whether an identity file content is protected by
+// a passphrase, either as an encrypted age identity or an encrypted SSH key.
+func IsEncryptedKeyFile(content []byte) bool {
+	if IsEncryptedIdentity(content) {
+		return true
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(content)), "-----BEGIN") {
+		return false
+	}
+	_, err := ssh.ParseRawPrivateKey(content)
+	var missing *ssh.PassphraseMissingError
+	return errors.As(err, &missing)
+}
+
+// decryptIdentity asks for the passphrase and returns the age secret key
+// stored in an encrypted identity file.
+func decryptIdentity(content []byte, passphrase PassphraseFunc) (string, error) {
+	if passphrase == nil {
+		return "", errors.New("identity is passphrase-protected and no passphrase source is available")
+	}
+
+	pass, err := passphrase()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	id, err := age.NewScryptIdentity(string(pass))
+	if err != nil {
+		return "", err
+	}
+
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(content)), id)
+	if err != nil {
+		return "", errors.New(errDecryptIdentity)
+	}
+
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return "", errors.New(errDecryptIdentity)
+	}
+
+	return strings.TrimSpace(string(plain)), nil
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/plugin"
+)
+
+const pluginIdentityPrefix = "AGE-PLUGIN-"
+
+// pluginIdentity is an identity handled by an external age-plugin-<name>
+// binary, together with the recipient noted in its identity file, if any.
+type pluginIdentity struct {
+	*plugin.Identity
+	publicKey string
+}
+
+// pluginUI lets plugins talk to the user (PIN prompts, touch requests)
+// through the terminal, keeping stdout free for command output.
+var pluginUI = plugin.NewTerminalUI(
+	func(format string, v ...any) { fmt.Fprintf(os.Stderr, format+"\n", v...) },
+	func(format string, v ...any) { fmt.Fprintf(os.Stderr, "warning: "+format+"\n", v...) },
+)
+
+// IsPluginRecipient reports whether pubKey is an age plugin recipient
+// (age1<plugin>1...), wrapped by the age-plugin-<plugin> binary found in $PATH.
+func IsPluginRecipient(pubKey string) bool {
+	pubKey = strings.ToLower(strings.TrimSpace(pubKey))
+	if !strings.HasPrefix(pubKey, "age1") || IsPostQuantumRecipient(pubKey) {
+		return false
+	}
+	// '1' is not part of the bech32 data alphabet, so the last one is the
+	// separator; X25519 recipients have it right after "age".
+	return strings.LastIndex(pubKey, "1") > len("age")
+}
+
+// parsePluginIdentity parses an AGE-PLUGIN-<NAME>-1... line. publicKey is
+// the recipient noted next to it in the identity file, if any.
+func parsePluginIdentity(line, publicKey string) (age.Identity, error) {
+	id, err := plugin.NewIdentity(line, pluginUI)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginIdentity{Identity: id, publicKey: publicKey}, nil
+}
@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/plugin"
+)
+
+// fakePluginName is the plugin the test binary plays when it is run as
+// age-plugin-envsealtest.
+const fakePluginName = "envsealtest"
+
+func TestMain(m *testing.M) {
+	if filepath.Base(os.Args[0]) == "age-plugin-"+fakePluginName {
+		os.Exit(runFakePlugin())
+	}
+
+	dir, err := os.MkdirTemp("", "envseal-plugin")
+	if err != nil {
+		panic(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Symlink(exe, filepath.Join(dir, "age-plugin-"+fakePluginName)); err != nil {
+		panic(err)
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// runFakePlugin implements a toy plugin: the recipient and identity data is
+// a key, and the file key is wrapped by XORing it with that key.
+func runFakePlugin() int {
+	p, err := plugin.New(fakePluginName)
+	if err != nil {
+		return 1
+	}
+	p.HandleRecipient(func(data []byte) (age.Recipient, error) {
+		return fakePluginKey(data), nil
+	})
+	p.HandleIdentity(func(data []byte) (age.Identity, error) {
+		return fakePluginKey(data), nil
+	})
+	return p.Main()
+}
+
+type fakePluginKey []byte
+
+func (k fakePluginKey) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	return []*age.Stanza{{Type: fakePluginName, Body: k.xor(fileKey)}}, nil
+}
+
+func (k fakePluginKey) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type == fakePluginName {
+			return k.xor(s.Body), nil
+		}
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+func (k fakePluginKey) xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ k[i%len(k)]
+	}
+	return out
+}
+
+func TestPluginRecipientWrapsDEK(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5a, 0xc3}, 16)
+	recipient := plugin.EncodeRecipient(fakePluginName, key)
+	identityFile := "# public key: " + recipient + "\n" + plugin.EncodeIdentity(fakePluginName, key) + "\n"
+
+	if !IsPluginRecipient(recipient) {
+		t.Fatalf("IsPluginRecipient(%q) = false", recipient)
+	}
+	if _, err := NormalizeRecipient(recipient); err != nil {
+		t.Fatalf("NormalizeRecipient: %v", err)
+	}
+
+	dek, err := GenerateDEK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := EncryptDEK(dek, []string{recipient})
+	if err != nil {
+		t.Fatalf("EncryptDEK: %v", err)
+	}
+
+	identities, err := ParseIdentities(identityFile)
+	if err != nil {
+		t.Fatalf("ParseIdentities: %v", err)
+	}
+	if len(identities) != 1 {
+		t.Fatalf("got %d identities, want 1", len(identities))
+	}
+	if pub, err := IdentityRecipient(identities[0]); err != nil || pub != recipient {
+		t.Fatalf("IdentityRecipient = %q, %v; want %q", pub, err, recipient)
+	}
+
+	got, err := DecryptDEK(enc, identities[0])
+	if err != nil {
+		t.Fatalf("DecryptDEK: %v", err)
+	}
+	if !bytes.Equal(got, dek) {
+		t.Fatal("DecryptDEK returned another key")
+	}
+}
+
+func TestPluginIdentityRejectsOtherRecipient(t *testing.T) {
+	recipient := plugin.EncodeRecipient(fakePluginName, bytes.Repeat([]byte{1}, 32))
+	other, err := ParseIdentities(plugin.EncodeIdentity(fakePluginName, bytes.Repeat([]byte{2}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dek, err := GenerateDEK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := EncryptDEK(dek, []string{recipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The toy plugin unwraps to a wrong file key, which age rejects.
+	if _, err := DecryptDEK(enc, other[0]); err == nil {
+		t.Fatal("DecryptDEK succeeded with the identity of another recipient")
+	}
+}
+
+func TestPluginIdentityWithoutPublicKey(t *testing.T) {
+	identities, err := ParseIdentities(plugin.EncodeIdentity(fakePluginName, []byte{1, 2, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := IdentityRecipient(identities[0]); err == nil {
+		t.Fatal("IdentityRecipient succeeded without a '# public key' comment")
+	}
+}
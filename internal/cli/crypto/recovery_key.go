@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const (
+	x25519IdentityHRP = "AGE-SECRET-KEY-"
+
+	recoveryKeySize      = 32
+	recoveryChecksumSize = 2
+
+	// RecoveryWordCount is the number of words of a recovery key: one per
+	// byte of the X25519 secret, followed by the checksum.
+	RecoveryWordCount = recoveryKeySize + recoveryChecksumSize
+)
+
+var errRecoveryChecksum = errors.New("recovery words do not match their checksum (a word is wrong or out of order)")
+
+// GenerateRecoveryKey creates a new X25519 identity for offline recovery and
+// returns it as a word list, together with its public key.
+func GenerateRecoveryKey() (words []string, pubKey string, err error) {
+	secret := make([]byte, recoveryKeySize)
+	if err := fillRandom(secret); err != nil {
+		return nil, "", err
+	}
+	defer zeroBytes(secret)
+
+	identity, err := recoveryIdentity(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return recoveryWords(secret), identity.Recipient().String(), nil
+}
+
+// recoveryWords encodes secret as one word per byte followed by the
+// checksum words.
+func recoveryWords(secret []byte) []string {
+	sum := sha256.Sum256(secret)
+	words := make([]string, 0, len(secret)+recoveryChecksumSize)
+	for _, b := range secret {
+		words = append(words, recoveryWordList[b])
+	}
+	for _, b := range sum[:recoveryChecksumSize] {
+		words = append(words, recoveryWordList[b])
+	}
+	return words
+}
+
+// ParseRecoveryWords rebuilds the identity of a recovery key from its words.
+// Words are separated by whitespace, may be abbreviated to their first four
+// letters (all words have at least four), and the item numbers of the printed list are ignored.
+func ParseRecoveryWords(text string) (*age.X25519Identity, error) {
+	byPrefix := make(map[string]byte, len(recoveryWordList))
+	for i, w := range recoveryWordList {
+		byPrefix[w[:4]] = byte(i)
+	}
+
+	var data []byte
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if strings.Trim(field, "0123456789.:)") == "" {
+			continue
+		}
+		if len(field) < 4 {
+			return nil, fmt.Errorf("%q is not a recovery word", field)
+		}
+		b, ok := byPrefix[field[:4]]
+		if !ok || !strings.HasPrefix(recoveryWordList[b], field) {
+			return nil, fmt.Errorf("%q is not a recovery word", field)
+		}
+		data = append(data, b)
+	}
+	defer zeroBytes(data)
+
+	if len(data) != RecoveryWordCount {
+		return nil, fmt.Errorf("recovery key has %d words, expected %d", len(data), RecoveryWordCount)
+	}
+
+	secret, checksum := data[:recoveryKeySize], data[recoveryKeySize:]
+	sum := sha256.Sum256(secret)
+	if string(sum[:recoveryChecksumSize]) != string(checksum) {
+		return nil, errRecoveryChecksum
+	}
+
+	return recoveryIdentity(secret)
+}
+
+func recoveryIdentity(secret []byte) (*age.X25519Identity, error) {
+	return age.ParseX25519Identity(bech32Encode(x25519IdentityHRP, secret))
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryWordList(t *testing.T) {
+	if !slices.IsSorted(recoveryWordList[:]) {
+		t.Error("the word list is not sorted")
+	}
+	prefixes := make(map[string]string)
+	for _, w := range recoveryWordList {
+		if len(w) < 4 || strings.ToLower(w) != w {
+			t.Errorf("%q is not a lowercase word of at least four letters", w)
+			continue
+		}
+		if other, dup := prefixes[w[:4]]; dup {
+			t.Errorf("%q and %q share their first four letters", other, w)
+		}
+		prefixes[w[:4]] = w
+	}
+}
+
+func TestRecoveryKeyRoundTrip(t *testing.T) {
+	words, pubKey, err := GenerateRecoveryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != RecoveryWordCount {
+		t.Fatalf("got %d words, want %d", len(words), RecoveryWordCount)
+	}
+
+	// The printed list is numbered; people may retype it in capitals or
+	// with only the first four letters of each word.
+	var numbered, prefixes strings.Builder
+	for i, w := range words {
+		fmt.Fprintf(&numbered, "%2d. %s\n", i+1, w)
+		fmt.Fprintf(&prefixes, "%s ", strings.ToUpper(w[:4]))
+	}
+
+	for name, text := range map[string]string{
+		"words":    strings.Join(words, " "),
+		"numbered": numbered.String(),
+		"prefixes": prefixes.String(),
+	} {
+		identity, err := ParseRecoveryWords(text)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := identity.Recipient().String(); got != pubKey {
+			t.Errorf("%s: public key %s, want %s", name, got, pubKey)
+		}
+	}
+}
+
+func TestParseRecoveryWordsRejectsMistakes(t *testing.T) {
+	secret := make([]byte, recoveryKeySize)
+	for i := range secret {
+		secret[i] = byte(i * 7)
+	}
+	words := recoveryWords(secret)
+	if _, err := ParseRecoveryWords(strings.Join(words, " ")); err != nil {
+		t.Fatal(err)
+	}
+
+	replaced := func(i int, w string) []string {
+		out := slices.Clone(words)
+		out[i] = w
+		return out
+	}
+	swapped := slices.Clone(words)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	otherWord := func(w string) string {
+		if w == recoveryWordList[0] {
+			return recoveryWordList[1]
+		}
+		return recoveryWordList[0]
+	}
+
+	tests := []struct {
+		name     string
+		words    []string
+		checksum bool // whether the checksum is what catches it
+	}{
+		{"wrong key word", replaced(5, otherWord(words[5])), true},
+		{"wrong checksum word", replaced(RecoveryWordCount-1, otherWord(words[RecoveryWordCount-1])), true},
+		{"words out of order", swapped, true},
+		{"missing word", words[1:], false},
+		{"extra word", append(slices.Clone(words), words[0]), false},
+		{"unknown word", replaced(3, "zzzz"), false},
+		{"prefix too short", replaced(3, words[3][:3]), false},
+		{"prefix of another word", replaced(3, words[3][:4]+"x"), false},
+	}
+	for _, tt := range tests {
+		_, err := ParseRecoveryWords(strings.Join(tt.words, " "))
+		if err == nil {
+			t.Errorf("%s: ParseRecoveryWords succeeded", tt.name)
+			continue
+		}
+		if got := errors.Is(err, errRecoveryChecksum); got != tt.checksum {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxShares is the number of distinct non-zero x coordinates in GF(2^8).
+const maxShares = 255
+
+// SplitSecret splits secret into n Shamir shares over GF(2^8), any threshold
+// of which reconstruct it. Each share is its x coordinate followed by one
+// polynomial evaluation per secret byte.
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret cannot be empty")
+	}
+	if threshold < 2 || threshold > n || n > maxShares {
+		return nil, fmt.Errorf("invalid threshold %d of %d shares (need 2 <= threshold <= shares <= %d)", threshold, n, maxShares)
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, 1+len(secret))
+		shares[i][0] = byte(i + 1)
+	}
+
+	coeffs := make([]byte, threshold)
+	defer zeroBytes(coeffs)
+	for pos, b := range secret {
+		coeffs[0] = b
+		if err := fillRandom(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[1+pos] = evalPolynomial(coeffs, share[0])
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret from shares produced by SplitSecret.
+// Fewer shares than the threshold yield a wrong secret rather than an error,
+// so callers must check the result.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+
+	size := len(shares[0])
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if len(s) != size || size < 2 {
+			return nil, errors.New("shares have inconsistent lengths")
+		}
+		if s[0] == 0 || seen[s[0]] {
+			return nil, errors.New("duplicate or invalid share")
+		}
+		seen[s[0]] = true
+	}
+
+	secret := make([]byte, size-1)
+	for pos := range secret {
+		var acc byte
+		for j, sj := range shares {
+			// Lagrange basis polynomial for share j, evaluated at x = 0.
+			basis := byte(1)
+			for m, sm := range shares {
+				if m == j {
+					continue
+				}
+				basis = gfMul(basis, gfMul(sm[0], gfInv(sm[0]^sj[0])))
+			}
+			acc ^= gfMul(sj[1+pos], basis)
+		}
+		secret[pos] = acc
+	}
+
+	return secret, nil
+}
+
+// evalPolynomial evaluates coeffs (constant term first) at x with Horner's rule.
+func evalPolynomial(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x + 1, in constant time.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= a & -(b & 1)
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a non-zero a (a^254).
+func gfInv(a byte) byte {
+	r := byte(1)
+	for i := 0; i < 7; i++ {
+		a = gfMul(a, a)
+		r = gfMul(r, a)
+	}
+	return r
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// subsets returns every subset of size k of the indexes 0..n-1.
+func subsets(n, k int) [][]int {
+	if k == 0 {
+		return [][]int{nil}
+	}
+	var out [][]int
+	for first := 0; first <= n-k; first++ {
+		for _, rest := range subsets(n-first-1, k-1) {
+			s := []int{first}
+			for _, r := range rest {
+				s = append(s, first+1+r)
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func pick(shares [][]byte, idx []int) [][]byte {
+	out := make([][]byte, len(idx))
+	for i, j := range idx {
+		out[i] = shares[j]
+	}
+	return out
+}
+
+func TestShamirSplitCombine(t *testing.T) {
+	tests := []struct{ threshold, n int }{
+		{2, 2},
+		{2, 3},
+		{3, 5},
+		{5, 5},
+		{4, 8},
+	}
+	for _, tt := range tests {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			t.Fatal(err)
+		}
+		shares, err := SplitSecret(secret, tt.n, tt.threshold)
+		if err != nil {
+			t.Fatalf("SplitSecret(%d of %d): %v", tt.threshold, tt.n, err)
+		}
+		if len(shares) != tt.n {
+			t.Fatalf("SplitSecret(%d of %d) returned %d shares", tt.threshold, tt.n, len(shares))
+		}
+
+		for _, idx := range subsets(tt.n, tt.threshold) {
+			got, err := CombineShares(pick(shares, idx))
+			if err != nil {
+				t.Fatalf("%d of %d, shares %v: %v", tt.threshold, tt.n, idx, err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("%d of %d, shares %v: wrong secret", tt.threshold, tt.n, idx)
+			}
+		}
+
+		// Below the threshold the shares reveal nothing: combining them
+		// yields another value (one share alone is refused).
+		if tt.threshold-1 < 2 {
+			if _, err := CombineShares(shares[:1]); err == nil {
+				t.Errorf("%d of %d: CombineShares accepted a single share", tt.threshold, tt.n)
+			}
+			continue
+		}
+		for _, idx := range subsets(tt.n, tt.threshold-1) {
+			got, err := CombineShares(pick(shares, idx))
+			if err != nil {
+				t.Fatalf("%d of %d, shares %v: %v", tt.threshold, tt.n, idx, err)
+			}
+			if bytes.Equal(got, secret) {
+				t.Errorf("%d of %d: %d shares %v recovered the secret", tt.threshold, tt.n, len(idx), idx)
+			}
+		}
+	}
+}
+
+func TestShamirSplitRejectsInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret       []byte
+		n, threshold int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold of one", []byte("k"), 3, 1},
+		{"threshold above shares", []byte("k"), 3, 4},
+		{"too many shares", []byte("k"), 256, 2},
+	}
+	for _, tt := range tests {
+		if _, err := SplitSecret(tt.secret, tt.n, tt.threshold); err == nil {
+			t.Errorf("%s: SplitSecret succeeded", tt.name)
+		}
+	}
+}
+
+func TestShamirCombineRejectsInvalidShares(t *testing.T) {
+	shares, err := SplitSecret([]byte("vault key"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeroX := bytes.Clone(shares[1])
+	zeroX[0] = 0
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"no shares", nil},
+		{"one share", shares[:1]},
+		{"same share twice", [][]byte{shares[0], shares[0]}},
+		{"duplicate x coordinate", [][]byte{shares[0], append([]byte{shares[0][0]}, shares[1][1:]...)}},
+		{"zero x coordinate", [][]byte{shares[0], zeroX}},
+		{"different lengths", [][]byte{shares[0], shares[1][:len(shares[1])-1]}},
+		{"x coordinate only", [][]byte{shares[0][:1], shares[1][:1]}},
+	}
+	for _, tt := range tests {
+		if _, err := CombineShares(tt.shares); err == nil {
+			t.Errorf("%s: CombineShares succeeded", tt.name)
+		}
+	}
+}
+
+func TestShamirCorruptedShareChangesSecret(t *testing.T) {
+	secret := []byte("vault key")
+	shares, err := SplitSecret(secret, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pos := 1; pos < len(shares[0]); pos++ {
+		corrupted := bytes.Clone(shares[0])
+		corrupted[pos] ^= 0x01
+		got, err := CombineShares([][]byte{corrupted, shares[1]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The scheme itself cannot tell: callers check the result (see
+		// config.UnlockWithShares).
+		if bytes.Equal(got, secret) {
+			t.Errorf("flipping byte %d of a share left the secret unchanged", pos)
+		}
+	}
+}
+
+func TestGF256Inverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := gfMul(byte(a), gfInv(byte(a))); got != 1 {
+			t.Errorf("%#02x * inverse = %#02x, want 1", a, got)
+		}
+	}
+}
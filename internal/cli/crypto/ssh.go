@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshEd25519KeyType = "ssh-ed25519"
+	sshRSAKeyType     = "ssh-rsa"
+)
+
+// sshIdentity is an SSH private key used as an age identity, together with
+// its public key in authorized_keys format (without comment).
+type sshIdentity struct {
+	age.Identity
+	publicKey string
+}
+
+// IsSSHRecipient reports whether s looks like an SSH public key rather than an age recipient.
+func IsSSHRecipient(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "ssh-")
+}
+
+// ParseSSHPublicKey parses an authorized_keys style line ("ssh-ed25519 AAAA... comment")
+// and returns the canonical recipient string (no comment) and the comment.
+// Only key types usable as age recipients (ssh-ed25519, ssh-rsa) are accepted.
+func ParseSSHPublicKey(line string) (recipient string, comment string, err error) {
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return "", "", fmt.Errorf("malformed SSH public key: %w", err)
+	}
+	if t := pub.Type(); t != sshEd25519KeyType && t != sshRSAKeyType {
+		return "", "", fmt.Errorf("unsupported SSH key type %q (use ssh-ed25519 or ssh-rsa)", t)
+	}
+	return marshalSSHPublicKey(pub), comment, nil
+}
+
+// parseSSHIdentity parses an OpenSSH or PEM private key. For passphrase-protected
+// keys the public key is read from the key itself (OpenSSH format) or from the
+// adjacent ".pub" file, and passphrase is only invoked once the key is needed.
+func parseSSHIdentity(path string, pemBytes []byte, passphrase PassphraseFunc) (age.Identity, error) {
+	key, err := ssh.ParseRawPrivateKey(pemBytes)
+	if err == nil {
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		id, err := agessh.ParseIdentity(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &sshIdentity{Identity: id, publicKey: marshalSSHPublicKey(signer.PublicKey())}, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, err
+	}
+	if passphrase == nil {
+		return nil, errors.New("SSH key is passphrase-protected and no passphrase source is available")
+	}
+
+	pub := missing.PublicKey
+	if pub == nil {
+		if path == "" {
+			return nil, errors.New("encrypted SSH key in PEM format needs a .pub file; use a key file or the OpenSSH format")
+		}
+		pubBytes, err := os.ReadFile(path + ".pub")
+		if err != nil {
+			return nil, fmt.Errorf("encrypted SSH key needs its public key at %s.pub: %w", path, err)
+		}
+		pub, _, _, _, err = ssh.ParseAuthorizedKey(pubBytes)
+		if err != nil {
+			return nil, fmt.Errorf("malformed SSH public key in %s.pub: %w", path, err)
+		}
+	}
+
+	id, err := agessh.NewEncryptedSSHIdentity(pub, pemBytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &sshIdentity{Identity: id, publicKey: marshalSSHPublicKey(pub)}, nil
+}
+
+func marshalSSHPublicKey(pub ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+}
@@ -0,0 +1,39 @@
+package crypto
+
+// recoveryWordList maps each byte value to a word for recovery keys. The
+// words are sorted and their first four letters are unique, so a word can
+// also be typed as its four-letter prefix.
+var recoveryWordList = [256]string{
+	"able", "acid", "acorn", "actor", "adapt", "admit", "adult", "agent",
+	"alarm", "album", "alert", "alpha", "amber", "angle", "ankle", "apple",
+	"april", "arena", "arrow", "atlas", "attic", "audio", "autumn", "bacon",
+	"badge", "baker", "bamboo", "banana", "banjo", "barrel", "basket", "beach",
+	"beaver", "berry", "bicycle", "bishop", "blanket", "blossom", "bonus", "border",
+	"bottle", "bracket", "breeze", "bridge", "bronze", "bucket", "butter", "cabin",
+	"cactus", "camera", "canal", "candle", "canyon", "carbon", "carpet", "castle",
+	"cattle", "cedar", "cherry", "circle", "citrus", "clover", "cobalt", "coconut",
+	"comet", "copper", "cotton", "cradle", "crayon", "cricket", "crystal", "dancer",
+	"debut", "delta", "denim", "desert", "diesel", "dinner", "divide", "doctor",
+	"dollar", "dolphin", "donkey", "dragon", "drum", "eagle", "easel", "echo",
+	"eclipse", "elbow", "elder", "ember", "empire", "engine", "fabric", "falcon",
+	"fender", "ferry", "fiddle", "finger", "flame", "flute", "forest", "fossil",
+	"fountain", "frog", "galaxy", "garden", "garlic", "gazelle", "ginger", "glacier",
+	"globe", "gospel", "gravel", "guitar", "habit", "hammer", "harbor", "harvest",
+	"hazel", "helmet", "hermit", "hockey", "honey", "horizon", "hotel", "humble",
+	"iceberg", "igloo", "income", "indigo", "insect", "island", "ivory", "jacket",
+	"jaguar", "jazz", "jelly", "jersey", "jigsaw", "jungle", "kayak", "kernel",
+	"kettle", "kidney", "kitten", "koala", "ladder", "lagoon", "lantern", "laptop",
+	"lemon", "lentil", "leopard", "letter", "lilac", "linen", "lizard", "lobster",
+	"locket", "lumber", "magnet", "mango", "marble", "meadow", "melon", "meteor",
+	"mirror", "mitten", "monkey", "mosaic", "muffin", "museum", "napkin", "nectar",
+	"needle", "nickel", "noodle", "nutmeg", "oasis", "ocean", "office", "olive",
+	"onion", "orange", "orbit", "orchid", "otter", "oyster", "paddle", "palace",
+	"panda", "parrot", "pebble", "pencil", "pepper", "piano", "pigeon", "pilot",
+	"planet", "pocket", "potato", "puzzle", "quartz", "quiver", "rabbit", "radar",
+	"radish", "raven", "ribbon", "river", "rocket", "rubber", "saddle", "salmon",
+	"sandal", "satin", "scarf", "sierra", "silver", "sketch", "socket", "spider",
+	"statue", "summer", "sunset", "tablet", "tailor", "teapot", "temple", "thunder",
+	"tiger", "tomato", "tractor", "trumpet", "tulip", "tunnel", "turtle", "umbrella",
+	"unicorn", "valley", "velvet", "violin", "vision", "volcano", "waffle", "walnut",
+	"walrus", "window", "winter", "wizard", "yacht", "yogurt", "zebra", "zigzag",
+}
@@ -0,0 +1,192 @@
+package envfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatDockerEnv = "docker-env"
+	FormatSystemd   = "systemd"
+	FormatK8sSecret = "k8s-secret"
+	FormatTFVars    = "tfvars"
+)
+
+// ExportFormats are the formats Format writes.
+var ExportFormats = []string{
+	FormatDotenv, FormatJSON, FormatYAML, FormatShell,
+	FormatDockerEnv, FormatSystemd, FormatK8sSecret, FormatTFVars,
+}
+
+var (
+	// plainValueRe matches values that need no quoting in dotenv and shell.
+	plainValueRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+	// shellNameRe and hclIdentRe match the variable names of a POSIX shell
+	// and of Terraform.
+	shellNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	hclIdentRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+)
+
+// Options are the settings of formats that need more than the variables.
+type Options struct {
+	// Name and Namespace are the metadata of a k8s-secret.
+	Name      string
+	Namespace string
+}
+
+// Format writes vars in the given format, in their order.
+func Format(vars []Var, format string, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatDotenv:
+		for _, v := range vars {
+			fmt.Fprintf(&buf, "%s=%s\n", v.Key, quoteDotenv(v.Value))
+		}
+
+	case FormatShell:
+		for _, v := range vars {
+			if !shellNameRe.MatchString(v.Key) {
+				return nil, fmt.Errorf("%s is not a valid shell variable name", v.Key)
+			}
+			fmt.Fprintf(&buf, "export %s=%s\n", v.Key, quoteShell(v.Value))
+		}
+
+	case FormatDockerEnv:
+		// docker --env-file takes each line verbatim: no quotes, no escapes.
+		for _, v := range vars {
+			if strings.ContainsAny(v.Value, "\r\n") {
+				return nil, fmt.Errorf("%s spans several lines, which %s files cannot hold", v.Key, FormatDockerEnv)
+			}
+			fmt.Fprintf(&buf, "%s=%s\n", v.Key, v.Value)
+		}
+
+	case FormatSystemd:
+		// EnvironmentFile= syntax: in double quotes a backslash escapes
+		// " \ ` $ and newlines are kept.
+		for _, v := range vars {
+			fmt.Fprintf(&buf, "%s=\"%s\"\n", v.Key, escapeChars(v.Value, "\"\\`$"))
+		}
+
+	case FormatJSON:
+		m := make(map[string]string, len(vars))
+		for _, v := range vars {
+			m[v.Key] = v.Value
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+
+	case FormatYAML:
+		if len(vars) == 0 {
+			return []byte("{}\n"), nil
+		}
+		return marshalYAML(stringMap(vars))
+
+	case FormatK8sSecret:
+		return formatK8sSecret(vars, opts)
+
+	case FormatTFVars:
+		for _, v := range vars {
+			if !hclIdentRe.MatchString(v.Key) {
+				return nil, fmt.Errorf("%s is not a valid Terraform variable name", v.Key)
+			}
+			fmt.Fprintf(&buf, "%s = %s\n", v.Key, quoteHCL(v.Value))
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown format %q (use %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	return buf.Bytes(), nil
+}
+
+// stringMap builds a YAML mapping of vars that keeps their order.
+func stringMap(vars []Var) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range vars {
+		m.Content = append(m.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v.Key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v.Value},
+		)
+	}
+	return m
+}
+
+func formatK8sSecret(vars []Var, opts Options) ([]byte, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("%s needs a name", FormatK8sSecret)
+	}
+	metadata := []Var{{Key: "name", Value: opts.Name}}
+	if opts.Namespace != "" {
+		metadata = append(metadata, Var{Key: "namespace", Value: opts.Namespace})
+	}
+	data := make([]Var, len(vars))
+	for i, v := range vars {
+		data[i] = Var{Key: v.Key, Value: base64.StdEncoding.EncodeToString([]byte(v.Value))}
+	}
+
+	doc := stringMap([]Var{{Key: "apiVersion", Value: "v1"}, {Key: "kind", Value: "Secret"}})
+	doc.Content = append(doc.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "metadata"}, stringMap(metadata),
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "type"}, &yaml.Node{Kind: yaml.ScalarNode, Value: "Opaque"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "data"}, stringMap(data),
+	)
+	return marshalYAML(doc)
+}
+
+func marshalYAML(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteDotenv leaves plain values bare and double-quotes the rest, with the
+// escapes Parse understands.
+func quoteDotenv(s string) string {
+	if s == "" || plainValueRe.MatchString(s) {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// quoteShell single-quotes s for a POSIX shell.
+func quoteShell(s string) string {
+	if s != "" && plainValueRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteHCL writes s as an HCL string, escaping template sequences so the
+// value is taken literally.
+func quoteHCL(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+	return `"` + r.Replace(s) + `"`
+}
+
+func escapeChars(s, chars string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(chars, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
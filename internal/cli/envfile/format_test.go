@@ -0,0 +1,117 @@
+package envfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+// trickyValues are values whose quoting a format can get wrong.
+var trickyValues = []string{
+	"",
+	"plain",
+	"with space",
+	" padded ",
+	`double "quotes"`,
+	"single 'quotes'",
+	"it's",
+	`$HOME and ${HOME} and $(date) and ` + "`date`",
+	"${",
+	"%{ x }",
+	`back\slash\`,
+	`\n is not a newline`,
+	"two\nlines\n",
+	"crlf\r\nline",
+	"tab\there",
+	"hash # not a comment",
+	"#start",
+	"semi;colon",
+	"ünïcödé ✓",
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	var vars []Var
+	for i, v := range trickyValues {
+		vars = append(vars, Var{Key: "K" + string(rune('A'+i)), Value: v})
+	}
+	for _, format := range []string{FormatDotenv, FormatShell, FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			out, err := Format(vars, format, Options{})
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			got, err := Parse(out, format)
+			if err != nil {
+				t.Fatalf("Parse: %v\n%s", err, out)
+			}
+			values := make(map[string]string, len(got))
+			for _, v := range got {
+				values[v.Key] = v.Value
+			}
+			for _, v := range vars {
+				if values[v.Key] != v.Value {
+					t.Errorf("%s: got %q, want %q\n%s", v.Key, values[v.Key], v.Value, out)
+				}
+			}
+			if len(got) != len(vars) {
+				t.Errorf("got %d variables, want %d", len(got), len(vars))
+			}
+		})
+	}
+}
+
+func TestFormatEscapes(t *testing.T) {
+	vars := []Var{{Key: "A", Value: "say \"hi\" to ${USER} at $HOME\\`x`\n%{ y }"}}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatDotenv, `A="say \"hi\" to \${USER} at \$HOME\\` + "`x`" + `\n%{ y }"` + "\n"},
+		{FormatShell, `export A='say "hi" to ${USER} at $HOME\` + "`x`\n%{ y }'\n"},
+		{FormatSystemd, `A="say \"hi\" to \${USER} at \$HOME\\` + "\\`x\\`\n%{ y }\"\n"},
+		{FormatTFVars, `A = "say \"hi\" to $${USER} at $HOME\\` + "`x`" + `\n%%{ y }"` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := Format(vars, tt.format, Options{})
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Format =\n%s\nwant\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRejectsInvalidNames(t *testing.T) {
+	tests := []struct {
+		format string
+		vars   []Var
+	}{
+		{FormatShell, []Var{{Key: "A.B", Value: "1"}}},
+		{FormatTFVars, []Var{{Key: "A.B", Value: "1"}}},
+		{FormatDockerEnv, []Var{{Key: "A", Value: "two\nlines"}}},
+		{FormatK8sSecret, []Var{{Key: "A", Value: "1"}}}, // no name
+	}
+	for _, tt := range tests {
+		if out, err := Format(tt.vars, tt.format, Options{}); err == nil {
+			t.Errorf("%s: Format succeeded:\n%s", tt.format, out)
+		}
+	}
+}
+
+func TestFormatKeepsOrder(t *testing.T) {
+	vars := []Var{{Key: "B", Value: "1"}, {Key: "A", Value: "2"}}
+	out, err := Format(vars, FormatDotenv, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(out, FormatDotenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Var{{Key: "B", Value: "1", Line: 1}, {Key: "A", Value: "2", Line: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,505 @@
+// Package envfile reads and writes environment variables in the plaintext
+// formats envseal imports from and exports to.
+package envfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatShell  = "shell"
+)
+
+// ImportFormats are the formats Parse reads.
+var ImportFormats = []string{FormatDotenv, FormatJSON, FormatYAML, FormatShell}
+
+var keyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Var is one variable read from a file, in file order.
+type Var struct {
+	Key   string
+	Value string
+	// Line is where the variable starts in the file (0 if unknown).
+	Line int
+}
+
+// DetectFormat guesses the format of path from its extension: .json, .yaml
+// or .yml, .sh, and dotenv for everything else (.env, .env.local, ...).
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".sh", ".bash", ".zsh":
+		return FormatShell
+	}
+	return FormatDotenv
+}
+
+// Parse reads the variables in data. A key defined twice keeps its last
+// value, at the position of its first definition.
+func Parse(data []byte, format string) ([]Var, error) {
+	var vars []Var
+	var err error
+	switch format {
+	case FormatDotenv:
+		vars, err = parseLines(string(data), false)
+	case FormatShell:
+		vars, err = parseLines(string(data), true)
+	case FormatJSON:
+		vars, err = parseJSON(data)
+	case FormatYAML:
+		vars, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q (use %s)", format, strings.Join(ImportFormats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dedupe(vars), nil
+}
+
+func dedupe(vars []Var) []Var {
+	seen := make(map[string]int, len(vars))
+	out := vars[:0]
+	for _, v := range vars {
+		if i, ok := seen[v.Key]; ok {
+			out[i].Value = v.Value
+			continue
+		}
+		seen[v.Key] = len(out)
+		out = append(out, v)
+	}
+	return out
+}
+
+// lineParser walks a dotenv or shell file. Quoted values may span lines.
+type lineParser struct {
+	src   string
+	pos   int
+	line  int
+	shell bool
+}
+
+func (p *lineParser) errorf(format string, args ...any) error {
+	return p.errorAt(p.line, format, args...)
+}
+
+// errorAt reports an error at line, such as the start of an unterminated
+// quote.
+func (p *lineParser) errorAt(line int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *lineParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *lineParser) peek() byte { return p.src[p.pos] }
+
+func (p *lineParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *lineParser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine consumes the rest of the current line, which may only hold
+// blanks and a comment.
+func (p *lineParser) skipLine() error {
+	p.skipBlanks()
+	if p.eof() {
+		return nil
+	}
+	switch p.peek() {
+	case '#':
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	case '\r', '\n':
+	default:
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	if !p.eof() && p.peek() == '\r' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.next()
+	}
+	return nil
+}
+
+// parseLines reads KEY=VALUE lines. Both formats accept comments, blank
+// lines and an "export " prefix. In dotenv, double-quoted values understand
+// \n, \t, \r, \", \\ and \$, single-quoted values are literal, and unquoted
+// values run to the end of the line or a " #" comment. In shell mode values
+// follow POSIX quoting instead: 'single', "double" (escaping only $ ` " \),
+// $'ANSI-C' and backslash escapes, which may be concatenated, and several
+// assignments may share a line when separated by ';'.
+func parseLines(src string, shell bool) ([]Var, error) {
+	p := &lineParser{src: strings.TrimPrefix(src, "\ufeff"), line: 1, shell: shell}
+	var vars []Var
+	for {
+		for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+			p.next()
+		}
+		if p.eof() {
+			return vars, nil
+		}
+		if p.peek() == '#' {
+			if err := p.skipLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		line := p.line
+		key := p.word()
+		if key == "export" || (shell && key == "declare") {
+			p.skipBlanks()
+			if key == "declare" {
+				if flags := p.word(); !strings.HasPrefix(flags, "-") || !strings.Contains(flags, "x") {
+					return nil, p.errorf("only 'declare -x' is supported")
+				}
+				p.skipBlanks()
+			}
+			key = p.word()
+		}
+		if !keyRe.MatchString(key) {
+			return nil, p.errorf("invalid key %q", key)
+		}
+
+		if shell {
+			if p.eof() || p.peek() != '=' {
+				return nil, p.errorf("expected = after %s", key)
+			}
+		} else {
+			p.skipBlanks()
+			if p.eof() || (p.peek() != '=' && p.peek() != ':') {
+				return nil, p.errorf("expected = after %s", key)
+			}
+		}
+		p.pos++
+
+		var value string
+		var err error
+		if shell {
+			value, err = p.shellValue()
+		} else {
+			value, err = p.dotenvValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, Var{Key: key, Value: value, Line: line})
+
+		if shell {
+			p.skipBlanks()
+			if !p.eof() && p.peek() == ';' {
+				p.pos++
+				continue
+			}
+		}
+		if err := p.skipLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// word reads up to the next blank, '=', ':' or end of line.
+func (p *lineParser) word() string {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\r\n=:", p.peek()) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *lineParser) dotenvValue() (string, error) {
+	p.skipBlanks()
+	if p.eof() {
+		return "", nil
+	}
+
+	switch quote := p.peek(); quote {
+	case '\'', '"', '`':
+		open := p.line
+		p.next()
+		var b strings.Builder
+		for {
+			if p.eof() {
+				return "", p.errorAt(open, "unterminated %c quote", quote)
+			}
+			c := p.next()
+			if c == quote {
+				return b.String(), nil
+			}
+			if c == '\\' && quote == '"' && !p.eof() {
+				e := p.next()
+				switch e {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				case '"', '\\', '$':
+					b.WriteByte(e)
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(e)
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		if p.peek() == '#' && strings.IndexByte(" \t", p.src[p.pos-1]) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.src[start:p.pos]), nil
+}
+
+func (p *lineParser) shellValue() (string, error) {
+	var b strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';':
+			return b.String(), nil
+
+		case c == '\'':
+			open := p.line
+			p.next()
+			for {
+				if p.eof() {
+					return "", p.errorAt(open, "unterminated ' quote")
+				}
+				c := p.next()
+				if c == '\'' {
+					break
+				}
+				b.WriteByte(c)
+			}
+
+		case c == '"':
+			open := p.line
+			p.next()
+			for {
+				if p.eof() {
+					return "", p.errorAt(open, `unterminated " quote`)
+				}
+				c := p.next()
+				if c == '"' {
+					break
+				}
+				if c == '$' || c == '`' {
+					return "", p.errorf("substitutions are not supported; quote the value with ' '")
+				}
+				if c == '\\' && !p.eof() && strings.IndexByte("$`\"\\\n", p.peek()) >= 0 {
+					if e := p.next(); e != '\n' {
+						b.WriteByte(e)
+					}
+					continue
+				}
+				b.WriteByte(c)
+			}
+
+		case c == '$' && strings.HasPrefix(p.src[p.pos:], "$'"):
+			p.pos += 2
+			s, err := p.ansiCQuoted()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+
+		case c == '$' || c == '`':
+			return "", p.errorf("substitutions are not supported; quote the value with ' '")
+
+		case c == '\\':
+			p.next()
+			if p.eof() {
+				return b.String(), nil
+			}
+			if e := p.next(); e != '\n' {
+				b.WriteByte(e)
+			}
+
+		default:
+			b.WriteByte(p.next())
+		}
+	}
+	return b.String(), nil
+}
+
+// ansiCQuoted reads the rest of a $'...' string.
+func (p *lineParser) ansiCQuoted() (string, error) {
+	open := p.line
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorAt(open, "unterminated $' quote")
+		}
+		c := p.next()
+		if c == '\'' {
+			return b.String(), nil
+		}
+		if c != '\\' || p.eof() {
+			b.WriteByte(c)
+			continue
+		}
+		switch e := p.next(); e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			end := p.pos
+			for end < len(p.src) && end-p.pos < 2 && strings.IndexByte("0123456789abcdefABCDEF", p.src[end]) >= 0 {
+				end++
+			}
+			n, err := strconv.ParseUint(p.src[p.pos:end], 16, 8)
+			if err != nil {
+				return "", p.errorf(`invalid \x escape`)
+			}
+			p.pos = end
+			b.WriteByte(byte(n))
+		default:
+			if e >= '0' && e <= '7' {
+				end := p.pos
+				for end < len(p.src) && end-p.pos < 2 && p.src[end] >= '0' && p.src[end] <= '7' {
+					end++
+				}
+				n, _ := strconv.ParseUint(string(e)+p.src[p.pos:end], 8, 8)
+				p.pos = end
+				b.WriteByte(byte(n))
+				continue
+			}
+			b.WriteByte(e) // \\, \', \" and unknown escapes
+		}
+	}
+}
+
+func parseJSON(data []byte) ([]Var, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("JSON input must be an object of KEY: value")
+	}
+
+	var vars []Var
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		key := tok.(string)
+		var raw any
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		value, err := scalarString(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, Var{Key: key, Value: value})
+	}
+	return vars, checkKeys(vars)
+}
+
+func parseYAML(data []byte) ([]Var, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("YAML input must be a map of KEY: value")
+	}
+
+	var vars []Var
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		if v.Kind == yaml.AliasNode {
+			v = v.Alias
+		}
+		if v.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: value of %s is not a string, number or boolean", v.Line, k.Value)
+		}
+		value := v.Value
+		if v.Tag == "!!null" {
+			value = ""
+		}
+		vars = append(vars, Var{Key: k.Value, Value: value, Line: k.Line})
+	}
+	return vars, checkKeys(vars)
+}
+
+// scalarString renders a JSON scalar as the value of an environment variable.
+func scalarString(key string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("value of %s is not a string, number or boolean", key)
+}
+
+func checkKeys(vars []Var) error {
+	for _, v := range vars {
+		if !keyRe.MatchString(v.Key) {
+			if v.Line > 0 {
+				return fmt.Errorf("line %d: invalid key %q", v.Line, v.Key)
+			}
+			return fmt.Errorf("invalid key %q", v.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package envfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		in     string
+		want   []Var
+	}{
+		{"plain", FormatDotenv, "A=1\nB = two\n", []Var{{"A", "1", 1}, {"B", "two", 2}}},
+		{"export and colon", FormatDotenv, "export A=1\nB: 2\n", []Var{{"A", "1", 1}, {"B", "2", 2}}},
+		{"empty", FormatDotenv, "A=\nB=''\nC=\"\"\n", []Var{{"A", "", 1}, {"B", "", 2}, {"C", "", 3}}},
+		{"comments and blank lines", FormatDotenv, "# header\n\nA=1 # note\n  # indented\nB=x#y\n",
+			[]Var{{"A", "1", 3}, {"B", "x#y", 5}}},
+		{"single quotes", FormatDotenv, `A='a \n $B "c" # d'`, []Var{{"A", `a \n $B "c" # d`, 1}}},
+		{"backquotes", FormatDotenv, "A=`it's`", []Var{{"A", "it's", 1}}},
+		{"double quote escapes", FormatDotenv, `A="a\nb\tc\r\"d\" \\ \$E \q"`, []Var{{"A", "a\nb\tc\r\"d\" \\ $E \\q", 1}}},
+		{"quoted value and comment", FormatDotenv, `A="x # y" # z`, []Var{{"A", "x # y", 1}}},
+		{"multi-line value", FormatDotenv, "A=\"one\ntwo\"\nB='three\nfour'\nC=5\n",
+			[]Var{{"A", "one\ntwo", 1}, {"B", "three\nfour", 3}, {"C", "5", 5}}},
+		{"CRLF", FormatDotenv, "A=1\r\nB=\"2\" \r\n# c\r\nC=3\r\n", []Var{{"A", "1", 1}, {"B", "2", 2}, {"C", "3", 4}}},
+		{"BOM", FormatDotenv, "\ufeffA=1\n", []Var{{"A", "1", 1}}},
+		{"duplicate keys", FormatDotenv, "A=1\nB=2\nA=3\n", []Var{{"A", "3", 1}, {"B", "2", 2}}},
+		{"no final newline", FormatDotenv, "A=1", []Var{{"A", "1", 1}}},
+
+		{"shell plain", FormatShell, "export A=1\nB=two\n", []Var{{"A", "1", 1}, {"B", "two", 2}}},
+		{"shell declare", FormatShell, "declare -x A=1\n", []Var{{"A", "1", 1}}},
+		{"shell single quotes", FormatShell, `A='it'\''s $HOME \n'`, []Var{{"A", `it's $HOME \n`, 1}}},
+		{"shell double quotes", FormatShell, `A="a \"b\" \\ \$ \q"`, []Var{{"A", `a "b" \ $ \q`, 1}}},
+		{"shell concatenation", FormatShell, `A=a'b c'"d"\ e`, []Var{{"A", "ab cd e", 1}}},
+		{"shell ANSI-C quotes", FormatShell, `A=$'a\nb\t\'c\' \\ \e\a'`, []Var{{"A", "a\nb\t'c' \\ \x1b\a", 1}}},
+		{"shell octal and hex escapes", FormatShell, `A=$'\101\0102\x43\x4a\x7'`, []Var{{"A", "A\x082CJ\a", 1}}},
+		{"shell multi-line value", FormatShell, "A='one\ntwo'\nB=\"three\nfour\"\nC=5\n",
+			[]Var{{"A", "one\ntwo", 1}, {"B", "three\nfour", 3}, {"C", "5", 5}}},
+		{"shell line continuation", FormatShell, "A=\"one \\\ntwo\"\nB=3\n", []Var{{"A", "one two", 1}, {"B", "3", 3}}},
+		{"shell separators", FormatShell, "A=1; export B='2';C=3 # c\nD=4\n",
+			[]Var{{"A", "1", 1}, {"B", "2", 1}, {"C", "3", 1}, {"D", "4", 2}}},
+		{"shell comments", FormatShell, "# header\nA=x#y # note\n", []Var{{"A", "x#y", 2}}},
+		{"shell CRLF", FormatShell, "A=1\r\nB='2'\r\n", []Var{{"A", "1", 1}, {"B", "2", 2}}},
+		{"shell BOM", FormatShell, "\ufeffA=1\n", []Var{{"A", "1", 1}}},
+		{"shell duplicate keys", FormatShell, "A=1\nB=2\nA=3\n", []Var{{"A", "3", 1}, {"B", "2", 2}}},
+
+		{"json", FormatJSON, `{"B": "x", "A": 1.50, "C": true, "D": null}`,
+			[]Var{{"B", "x", 0}, {"A", "1.50", 0}, {"C", "true", 0}, {"D", "", 0}}},
+		{"yaml", FormatYAML, "B: x\nA: 1.50\nC: |\n  multi\n  line\nD:\n",
+			[]Var{{"B", "x", 1}, {"A", "1.50", 2}, {"C", "multi\nline\n", 3}, {"D", "", 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.in), tt.format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		in     string
+		want   string
+	}{
+		{"invalid key", FormatDotenv, "A=1\n\n1A=2\n", `line 3: invalid key "1A"`},
+		{"missing =", FormatDotenv, "A=1\nB\n", "line 2: expected = after B"},
+		{"unterminated quote", FormatDotenv, "A=1\nB=\"x\ny\n", `line 2: unterminated " quote`},
+		{"text after quote", FormatDotenv, "A=\"x\"y\n", `line 1: unexpected 'y' after value`},
+		{"line after multi-line value", FormatDotenv, "A='x\ny'\nB\n", "line 3: expected = after B"},
+		{"CRLF line numbers", FormatDotenv, "A=1\r\n\r\nB\r\n", "line 3: expected = after B"},
+
+		{"shell blank before =", FormatShell, "A =1\n", "line 1: expected = after A"},
+		{"shell substitution", FormatShell, "A=1\nB=$HOME\n", "line 2: substitutions are not supported"},
+		{"shell substitution in double quotes", FormatShell, `A="${HOME}"`, "line 1: substitutions are not supported"},
+		{"shell command substitution", FormatShell, "A=`date`", "line 1: substitutions are not supported"},
+		{"shell declare", FormatShell, "declare -r A=1\n", "line 1: only 'declare -x' is supported"},
+		{"shell unterminated ANSI-C quote", FormatShell, "A=1\nB=$'x\n", "line 2: unterminated $' quote"},
+		{"shell unterminated quote", FormatShell, "A='x\n\ny\n", "line 1: unterminated ' quote"},
+		{"shell invalid hex escape", FormatShell, `A=$'\xg'`, `line 1: invalid \x escape`},
+
+		{"json not an object", FormatJSON, `["A"]`, "must be an object"},
+		{"json nested value", FormatJSON, `{"A": {"B": 1}}`, "value of A is not a string"},
+		{"json invalid key", FormatJSON, `{"A B": "1"}`, `invalid key "A B"`},
+		{"yaml nested value", FormatYAML, "A: 1\nB:\n  - x\n", "line 3: value of B is not a string"},
+		{"yaml invalid key", FormatYAML, "A: 1\nA B: 2\n", `line 2: invalid key "A B"`},
+		{"unknown format", "toml", "", `unknown format "toml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.in), tt.format)
+			if err == nil {
+				t.Fatalf("Parse succeeded, want %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +16,11 @@ const (
 	serviceType = "_envsync._tcp"
 	domain      = "local."
 	txtKey      = "pubkey="
+
+	// A DNS TXT string holds at most 255 bytes, less than a post-quantum
+	// public key. Longer keys are split into numbered "pubkey.N=" chunks.
+	txtChunkPrefix = "pubkey."
+	txtChunkSize   = 200
 )
 
 // BroadcastSession holds the server and a channel to notify when an ACK is received.
@@ -53,7 +59,7 @@ func BroadcastKey(code string, pubKey string) (*BroadcastSession, error) {
 		}
 	}()
 
-	txtRecord := []string{fmt.Sprintf("%s%s", txtKey, pubKey)}
+	txtRecord := encodePubKeyTXT(pubKey)
 
 	// Register the mDNS service USING the dynamic TCP port
 	service, err := mdns.NewMDNSService(code, serviceType, domain, host, port, nil, txtRecord)
@@ -87,38 +93,38 @@ func DiscoverKey(ctx context.Context, code string) (*DiscoverResult, error) {
 
 	go func() {
 		for entry := range entries {
-			if strings.Contains(entry.Name, code) {
-				for _, txt := range entry.InfoFields {
-					if after, ok := strings.CutPrefix(txt, txtKey); ok {
-						pubKey := after
-
-						// Capture connection info for the ACK
-						var targetIP net.IP
-						if entry.AddrV4 != nil {
-							targetIP = entry.AddrV4
-						}
-						targetPort := entry.Port
-
-						// Create the ACK closure
-						ackFunc := func() {
-							if targetIP != nil && targetPort > 0 {
-								addr := net.JoinHostPort(targetIP.String(), fmt.Sprintf("%d", targetPort))
-								// Brief timeout: if it fails, it fails silently, no big deal.
-								conn, _ := net.DialTimeout("tcp4", addr, 1*time.Second)
-								if conn != nil {
-									conn.Close()
-								}
-							}
-						}
-
-						select {
-						case resultChan <- &DiscoverResult{PubKey: pubKey, SendAck: ackFunc}:
-						default:
-						}
-						return
+			if !strings.Contains(entry.Name, code) {
+				continue
+			}
+			pubKey, ok := decodePubKeyTXT(entry.InfoFields)
+			if !ok {
+				continue
+			}
+
+			// Capture connection info for the ACK
+			var targetIP net.IP
+			if entry.AddrV4 != nil {
+				targetIP = entry.AddrV4
+			}
+			targetPort := entry.Port
+
+			// Create the ACK closure
+			ackFunc := func() {
+				if targetIP != nil && targetPort > 0 {
+					addr := net.JoinHostPort(targetIP.String(), fmt.Sprintf("%d", targetPort))
+					// Brief timeout: if it fails, it fails silently, no big deal.
+					conn, _ := net.DialTimeout("tcp4", addr, 1*time.Second)
+					if conn != nil {
+						conn.Close()
 					}
 				}
 			}
+
+			select {
+			case resultChan <- &DiscoverResult{PubKey: pubKey, SendAck: ackFunc}:
+			default:
+			}
+			return
 		}
 	}()
 
@@ -149,3 +155,55 @@ func DiscoverKey(ctx context.Context, code string) (*DiscoverResult, error) {
 		return nil, fmt.Errorf("code not found on local network (timeout or canceled)")
 	}
 }
+
+// encodePubKeyTXT builds the TXT strings announcing pubKey. Keys that fit in a
+// single string keep the original "pubkey=" form for older clients.
+func encodePubKeyTXT(pubKey string) []string {
+	if len(txtKey)+len(pubKey) <= txtChunkSize {
+		return []string{txtKey + pubKey}
+	}
+
+	var out []string
+	for i := 0; len(pubKey) > 0; i++ {
+		n := min(txtChunkSize, len(pubKey))
+		out = append(out, fmt.Sprintf("%s%d=%s", txtChunkPrefix, i, pubKey[:n]))
+		pubKey = pubKey[n:]
+	}
+	return out
+}
+
+// decodePubKeyTXT reassembles a public key announced by encodePubKeyTXT.
+func decodePubKeyTXT(fields []string) (string, bool) {
+	chunks := make(map[int]string)
+	for _, txt := range fields {
+		if after, ok := strings.CutPrefix(txt, txtKey); ok {
+			return after, true
+		}
+		rest, ok := strings.CutPrefix(txt, txtChunkPrefix)
+		if !ok {
+			continue
+		}
+		idx, chunk, ok := strings.Cut(rest, "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(idx)
+		if err != nil || n < 0 {
+			continue
+		}
+		chunks[n] = chunk
+	}
+	if len(chunks) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(chunks); i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return "", false
+		}
+		b.WriteString(chunk)
+	}
+	return b.String(), true
+}